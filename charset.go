@@ -0,0 +1,102 @@
+package htmlx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Charset tells how to transcode an input to UTF-8.
+// The zero value sniffs the encoding the way the HTML spec does:
+// from a byte order mark, a <meta charset> or http-equiv in the first
+// 1024 bytes, and finally guessing UTF-8 or windows-1252.
+type Charset struct {
+	// ContentType is the HTTP Content-Type header, if any;
+	// its charset parameter is used unless there is a byte order mark.
+	ContentType string
+
+	// Label, like "windows-1250" or "sjis", overrides the detection.
+	Label string
+}
+
+// Reader returns r transcoded to UTF-8, without a byte order mark.
+func (cs Charset) Reader(r io.Reader) (io.Reader, error) {
+	r, _, err := cs.ReaderName(r)
+	return r, err
+}
+
+// ReaderName is like Reader, also returning the canonical name
+// of the encoding found, like "windows-1250".
+func (cs Charset) ReaderName(r io.Reader) (io.Reader, string, error) {
+	var enc encoding.Encoding
+	var name string
+	if cs.Label == "" {
+		br := bufio.NewReaderSize(r, 1024)
+		head, err := br.Peek(1024)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, "", err
+		}
+		enc, name, _ = charset.DetermineEncoding(head, cs.ContentType)
+		r = br
+	} else {
+		if enc, name = charset.Lookup(cs.Label); enc == nil {
+			return nil, "", fmt.Errorf("unknown charset: %q", cs.Label)
+		}
+	}
+	if name != "utf-8" {
+		r = transform.NewReader(r, enc.NewDecoder())
+	}
+
+	br := bufio.NewReader(r)
+	if c, _, err := br.ReadRune(); err == nil && c != '\uFEFF' {
+		br.UnreadRune()
+	}
+	return br, name, nil
+}
+
+// Writer returns w encoding UTF-8 into the Label encoding, writing
+// the characters it lacks as numeric character references.
+// Close flushes the output, leaving w open.
+func (cs Charset) Writer(w io.Writer) (io.WriteCloser, error) {
+	enc, name := charset.Lookup(cs.Label)
+	switch {
+	case enc == nil:
+		return nil, fmt.Errorf("unknown charset: %q", cs.Label)
+	case name == "utf-8":
+		return nopCloser{w}, nil
+	case strings.HasPrefix(name, "utf-16"):
+		return nil, fmt.Errorf("can't write %s", name)
+	}
+	return transform.NewWriter(w, encoding.HTMLEscapeUnsupported(enc.NewEncoder())), nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Name returns the canonical name of the Label encoding,
+// or an error if it is unknown.
+func (cs Charset) Name() (string, error) {
+	_, name := charset.Lookup(cs.Label)
+	if name == "" {
+		return "", fmt.Errorf("unknown charset: %q", cs.Label)
+	}
+	return name, nil
+}
+
+// FinderFromDataWithCharset parses like FinderFromData,
+// transcoding the input to UTF-8 first.
+func FinderFromDataWithCharset(r io.Reader, cs Charset) (Finder, error) {
+	r, err := cs.Reader(r)
+	if err != nil {
+		return Finder{}, err
+	}
+	return FinderFromData(r)
+}
@@ -0,0 +1,107 @@
+package htmlx
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	p "github.com/wkhere/htmlx/pred"
+)
+
+func TestFinderFromDataWithCharset(t *testing.T) {
+	tab := []struct {
+		data string
+		cs   Charset
+		exp  string
+	}{
+		{"<p>Za\xbf\xf3\xb3\xe6", Charset{Label: "windows-1250"}, "Zażółć"},
+		{`<meta charset="windows-1250"><p>` + "Za\xbf\xf3\xb3\xe6", Charset{}, "Zażółć"},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-2">` +
+			"<p>\xb1", Charset{}, "ą"},
+		{"<p>\x93\xfa\x96\x7b", Charset{ContentType: "text/html; charset=Shift_JIS"}, "日本"},
+		{"\xef\xbb\xbf<p>\xc4\x85", Charset{ContentType: "text/html; charset=latin1"}, "ą"},
+		{"\xff\xfe<\x00p\x00>\x00\x05\x01", Charset{}, "ą"},
+		{"<p>\xc4\x85</p>", Charset{}, "ą"},
+		{"<p>\xe9", Charset{}, "é"},
+	}
+	for i, tc := range tab {
+		top, err := FinderFromDataWithCharset(strings.NewReader(tc.data), tc.cs)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		if res := top.Find(p.IsText()).Data; res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+
+	if _, err := FinderFromDataWithCharset(strings.NewReader(""), Charset{}); err != nil {
+		t.Errorf("empty input: %v", err)
+	}
+
+	_, err := FinderFromDataWithCharset(strings.NewReader(""), Charset{Label: "bogus"})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCharsetName(t *testing.T) {
+	if name, err := (Charset{Label: "sjis"}).Name(); err != nil || name != "shift_jis" {
+		t.Errorf("mismatch: %q %v", name, err)
+	}
+	if _, err := (Charset{Label: "bogus"}).Name(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCharsetWriter(t *testing.T) {
+	tab := []struct {
+		label string
+		data  string
+		exp   string
+	}{
+		{"windows-1250", "Zażółć", "Za\xbf\xf3\xb3\xe6"},
+		{"latin2", "ą 日", "\xb1 &#26085;"},
+		{"utf8", "ą", "ą"},
+	}
+	for i, tc := range tab {
+		var b strings.Builder
+		w, err := Charset{Label: tc.label}.Writer(&b)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		io.WriteString(w, tc.data)
+		if err := w.Close(); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%q\nexp:\n%q", i, res, tc.exp)
+		}
+
+		r, name, err := Charset{Label: tc.label}.ReaderName(strings.NewReader(b.String()))
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		back, _ := io.ReadAll(r)
+		if exp, _ := (Charset{Label: tc.label}).Name(); name != exp {
+			t.Errorf("tc[%d] name: got %q, exp %q", i, name, exp)
+		}
+		if tc.label != "latin2" && string(back) != tc.data {
+			t.Errorf("tc[%d] read back: got %q", i, back)
+		}
+	}
+
+	for _, label := range []string{"utf-16le", "bogus"} {
+		if _, err := (Charset{Label: label}).Writer(io.Discard); err == nil {
+			t.Errorf("%s: expected error", label)
+		}
+	}
+
+	_, name, err := Charset{}.ReaderName(strings.NewReader(
+		`<meta charset="windows-1250"><p>` + "Za\xbf"))
+	if err != nil || name != "windows-1250" {
+		t.Errorf("detected: got %q, %v", name, err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/recipe"
+)
+
+func parseArgs(args []string) (c config, err error) {
+	var help bool
+	var path string
+
+	fs := pflag.NewFlagSet("htmlextract", pflag.ContinueOnError)
+	fs.SortFlags = false
+
+	fs.StringVarP(&path, "recipe", "r", "",
+		"the recipe file, in YAML or JSON")
+
+	fs.BoolVar(&c.pretty, "pretty", false,
+		"indent the JSON output")
+
+	fs.StringVar(&c.charset, "charset", "",
+		"input encoding, like windows-1250; detected by default")
+
+	fs.StringArrayVar(&c.include, "include", nil,
+		"take the files matching this pattern from directories and archives,\n"+
+			"instead of *.html, *.htm and *.xhtml, also compressed")
+
+	fs.StringArrayVar(&c.exclude, "exclude", nil,
+		"skip the files matching this pattern in directories and archives")
+
+	fs.BoolVarP(&help, "help", "h", false, "show this help and exit")
+
+	err = fs.Parse(args)
+	if err != nil {
+		return c, err
+	}
+
+	if help {
+		c.help = func(w io.Writer) {
+			fs.SetOutput(w)
+			p := func(s string) { fmt.Fprintln(fs.Output(), s) }
+			p("Usage:")
+			p("\thtmlextract -r recipe.yaml [file|dir|glob|archive...] <stdin")
+			p("Output:")
+			p("\ta JSON object per input, as {\"input\": name, \"data\": object}")
+			p("\tif there are several; the field errors go to stderr")
+			p("Recipe:")
+			p("\tfields:")
+			p("\t  - name: title      # the key in the output")
+			p("\t    select: h1       # CSS selector, under the enclosing field")
+			p("\t    attr: NAME       # take an attribute instead of the text")
+			p("\t    html: true       # take the HTML instead of the text")
+			p("\t    trim: true       # trim and collapse the whitespace")
+			p("\t    regexp: RE       # take the match, or its first group")
+			p("\t    type: int        # string, int, float, bool or date")
+			p("\t    layout: LAYOUT   # the Go time layout of a date")
+			p("\t    list: true       # take all the matches")
+			p("\t    required: true   # report a missing value")
+			p("\t    default: VALUE   # of a missing value otherwise")
+			p("\t    fields: [...]    # extract an object from each match")
+			p("Flags:")
+			fs.PrintDefaults()
+		}
+		return c, nil
+	}
+
+	if path == "" {
+		return c, fmt.Errorf("no recipe; see htmlextract -h")
+	}
+	if c.recipe, err = recipe.Load(path); err != nil {
+		return c, err
+	}
+	if c.charset != "" {
+		if _, err = (htmlx.Charset{Label: c.charset}).Name(); err != nil {
+			return c, err
+		}
+	}
+
+	c.args = fs.Args()
+	if len(c.args) == 0 {
+		c.args = []string{"-"}
+	}
+	return c, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/input"
+	"github.com/wkhere/htmlx/recipe"
+)
+
+type config struct {
+	recipe  *recipe.Recipe
+	pretty  bool
+	charset string
+	include []string
+	exclude []string
+
+	args []string
+	help func(io.Writer)
+}
+
+// result is the output line of an input, when there are several.
+type result struct {
+	Input string          `json:"input"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// extract runs the recipe on the inputs, writing the results and
+// reporting the field errors to ew; it tells if all went well.
+func extract(w, ew io.Writer, conf config) (ok bool) {
+	ok = true
+	report := func(name string, err error) {
+		ok = false
+		prefix := "htmlextract: "
+		if name != "" {
+			prefix += name + ": "
+		}
+		var ee recipe.Errors
+		if !errors.As(err, &ee) {
+			fmt.Fprintln(ew, prefix+err.Error())
+			return
+		}
+		for _, e := range ee {
+			fmt.Fprintln(ew, prefix+e.Error())
+		}
+	}
+
+	var list []input.Input
+	for _, arg := range conf.args {
+		if arg == "-" {
+			list = append(list, input.Input{
+				Name: "-",
+				Open: func() (io.ReadCloser, error) { return io.NopCloser(os.Stdin), nil },
+			})
+			continue
+		}
+		l, err := input.Options{Include: conf.include, Exclude: conf.exclude}.List(arg)
+		if err != nil {
+			report(arg, err)
+			continue
+		}
+		list = append(list, l...)
+	}
+	multi := len(list) > 1 || len(conf.args) > 1
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if conf.pretty {
+		enc.SetIndent("", "  ")
+	}
+
+	for _, in := range list {
+		name := ""
+		if multi {
+			name = in.Name
+		}
+		data, err := extractInput(in, conf)
+		if err != nil {
+			report(name, err)
+		}
+		if data == nil {
+			continue
+		}
+		var v any = data
+		if multi {
+			v = result{in.Name, data}
+		}
+		if err := enc.Encode(v); err != nil {
+			report(name, err)
+			return false
+		}
+	}
+	return ok
+}
+
+func extractInput(in input.Input, conf config) (json.RawMessage, error) {
+	r, err := in.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	doc, err := htmlx.FinderFromDataWithCharset(r,
+		htmlx.Charset{ContentType: in.ContentType, Label: conf.charset})
+	if err != nil {
+		return nil, err
+	}
+	return conf.recipe.Extract(doc)
+}
+
+func main() {
+	conf, err := parseArgs(os.Args[1:])
+	if err != nil {
+		die(2, err)
+	}
+	if conf.help != nil {
+		conf.help(os.Stdout)
+		os.Exit(0)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	ok := extract(w, os.Stderr, conf)
+	w.Flush()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func die(code int, err error) {
+	fmt.Fprintln(os.Stderr, "htmlextract:", err)
+	os.Exit(code)
+}
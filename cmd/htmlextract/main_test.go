@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx/recipe"
+)
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.html", `<h1>A</h1><p class="n">1</p>`)
+	b := write("b.html", `<h1>B</h1><p class="n">x</p>`)
+	c := write("c.html", `<p>none</p>`)
+
+	r, err := recipe.Parse([]byte(`
+fields:
+  - {name: title, select: h1, required: true}
+  - {name: n, select: .n, type: int}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tab := []struct {
+		args []string
+		exp  string
+		errs string
+		ok   bool
+	}{
+		{[]string{a}, `{"title":"A","n":1}` + "\n", "", true},
+		{[]string{a, b, c},
+			`{"input":"` + a + `","data":{"title":"A","n":1}}` + "\n" +
+				`{"input":"` + b + `","data":{"title":"B","n":null}}` + "\n" +
+				`{"input":"` + c + `","data":{"title":null,"n":null}}` + "\n",
+			"htmlextract: " + b + ": n: not an int: \"x\"\n" +
+				"htmlextract: " + c + ": title: required value missing\n",
+			false},
+		{[]string{c}, `{"title":null,"n":null}` + "\n",
+			"htmlextract: title: required value missing\n", false},
+	}
+	for i, tc := range tab {
+		var w, ew strings.Builder
+		ok := extract(&w, &ew, config{recipe: r, args: tc.args})
+		if res := w.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+		if errs := ew.String(); errs != tc.errs {
+			t.Errorf("tc[%d] errors mismatch:\ngot:\n%s\nexp:\n%s", i, errs, tc.errs)
+		}
+		if ok != tc.ok {
+			t.Errorf("tc[%d] ok=%v, exp %v", i, ok, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/pflag"
+
+	"github.com/wkhere/htmlx"
+)
+
+func parseArgs(args []string) (c config, err error) {
+	var help bool
+
+	fs := pflag.NewFlagSet("htmlq", pflag.ContinueOnError)
+	fs.SortFlags = false
+
+	fs.BoolVarP(&c.raw, "raw", "r", false,
+		"print strings without quotes")
+
+	fs.BoolVarP(&c.compact, "compact", "c", false,
+		"print objects and lists on one line")
+
+	fs.StringVar(&c.charset, "charset", "",
+		"input encoding, like windows-1250; detected by default")
+
+	fs.BoolVarP(&help, "help", "h", false, "show this help and exit")
+
+	err = fs.Parse(args)
+	if err != nil {
+		return c, err
+	}
+
+	if help {
+		c.help = func(w io.Writer) {
+			fs.SetOutput(w)
+			p := func(s string) { fmt.Fprintln(fs.Output(), s) }
+			p("Usage:")
+			p("\thtmlq [flags] query [file...] <stdin")
+			p("Query:")
+			p("\tstages separated by |, each taking the results of the previous one:")
+			p("\tSEL            descendants matching a CSS selector")
+			p("\tfilter(SEL)    the nodes matching a CSS selector")
+			p("\tparent         the parents")
+			p("\tchildren       the child elements")
+			p("\tfirst, last    the first or last result")
+			p("\tlimit(N)       the first N results")
+			p("\tskip(N)        the results but the first N")
+			p("\tcount          the number of results")
+			p("\ttext           the text of the nodes")
+			p("\thtml           the HTML of the nodes")
+			p("\tattr(NAME)     the attribute values, of the nodes having it")
+			p("\t{KEY: QUERY}   an object per node, of the first results of the queries")
+			p("\tlist(QUERY)    a list per node, of the results of the query")
+			p("\ta selector named like a stage, like the svg element text, is quoted: \"text\"")
+			p("Example:")
+			p("\thtmlq -r 'a | attr(\"href\")' <page.html")
+			p("\thtmlq 'tr | {name: td:first-child | text, link: a | attr(href)}'")
+			p("\thtmlq '\"html\" | attr(lang)'")
+			p("Flags:")
+			fs.PrintDefaults()
+		}
+		return c, nil
+	}
+
+	if fs.NArg() == 0 {
+		return c, fmt.Errorf("no query; see htmlq -h")
+	}
+	if c.query, err = compile(fs.Arg(0)); err != nil {
+		return c, err
+	}
+	if c.charset != "" {
+		if _, err = (htmlx.Charset{Label: c.charset}).Name(); err != nil {
+			return c, err
+		}
+	}
+
+	c.args = fs.Args()[1:]
+	if len(c.args) == 0 {
+		c.args = []string{"-"}
+	}
+	return c, nil
+}
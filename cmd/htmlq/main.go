@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/input"
+)
+
+type config struct {
+	query   *query
+	raw     bool
+	compact bool
+	charset string
+
+	args []string
+	help func(io.Writer)
+}
+
+// run applies the query to a document, writing the results
+// a line each, or indented if they are objects or lists.
+func run(w io.Writer, r io.Reader, contentType string, conf config) error {
+	doc, err := htmlx.FinderFromDataWithCharset(r,
+		htmlx.Charset{ContentType: contentType, Label: conf.charset})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if !conf.compact {
+		enc.SetIndent("", "  ")
+	}
+
+	for f := range conf.query.run(doc.StreamSelf()) {
+		v := jsonOf(f.Node)
+		if conf.raw {
+			if s, ok := rawString(v); ok {
+				if _, err := fmt.Fprintln(w, s); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawString tells if v is a string, returning it unquoted.
+func rawString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.RawMessage:
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func process(w io.Writer, arg string, conf config) error {
+	if arg == "-" {
+		return run(w, os.Stdin, "", conf)
+	}
+	list, err := input.Options{}.List(arg)
+	if err != nil {
+		return err
+	}
+	for _, in := range list {
+		r, err := in.Open()
+		if err != nil {
+			return err
+		}
+		err = run(w, r, in.ContentType, conf)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", in.Name, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	conf, err := parseArgs(os.Args[1:])
+	if err != nil {
+		die(2, err)
+	}
+	if conf.help != nil {
+		conf.help(os.Stdout)
+		os.Exit(0)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, arg := range conf.args {
+		if err := process(w, arg, conf); err != nil {
+			w.Flush()
+			die(1, err)
+		}
+	}
+}
+
+func die(code int, err error) {
+	fmt.Fprintln(os.Stderr, "htmlq:", err)
+	os.Exit(code)
+}
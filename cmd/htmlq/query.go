@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/extract"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// A query is a compiled pipeline of stages, each a FinderStream
+// combinator. The stages producing values, like text, map the nodes
+// into raw nodes holding JSON, so the values flow as Finders too.
+type query struct {
+	stages []stage
+	out    kind
+}
+
+type stage struct {
+	name string
+	run  func(htmlx.FinderStream) htmlx.FinderStream
+}
+
+type kind int
+
+const (
+	nodes kind = iota
+	values
+)
+
+func (k kind) String() string {
+	if k == nodes {
+		return "nodes"
+	}
+	return "values"
+}
+
+func (q *query) run(ff htmlx.FinderStream) htmlx.FinderStream {
+	for _, s := range q.stages {
+		ff = s.run(ff)
+	}
+	return ff
+}
+
+// compile parses a pipeline, like: ul.menu li | {title: a | text, url: a | attr("href")}.
+// A quoted stage is a selector, even if named like another stage,
+// as "text" for the svg element.
+func compile(src string) (*query, error) {
+	parts, err := split(src, '|')
+	if err != nil {
+		return nil, err
+	}
+	q := &query{}
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return nil, errors.New("empty pipeline stage")
+		}
+		if err := q.add(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return q, nil
+}
+
+func (q *query) add(src string) error {
+	name, arg, call := strings.Cut(src, "(")
+	if call && !strings.HasPrefix(src, "{") {
+		if !strings.HasSuffix(arg, ")") {
+			return errors.New("missing )")
+		}
+		arg = strings.TrimSpace(arg[:len(arg)-1])
+		if !isIdent(name) {
+			// A selector, like li:nth-child(2).
+			name, arg, call = "", "", false
+		}
+	}
+
+	needNodes := func() error {
+		if q.out != nodes {
+			return fmt.Errorf("needs nodes, got %s", q.out)
+		}
+		return nil
+	}
+	noArg := func() error {
+		if call {
+			return errors.New("takes no argument")
+		}
+		return needNodes()
+	}
+	push := func(run func(htmlx.FinderStream) htmlx.FinderStream, out kind) error {
+		q.stages = append(q.stages, stage{src, run})
+		q.out = out
+		return nil
+	}
+
+	switch {
+	case src == ".":
+		return nil
+
+	case name == "text" || name == "html":
+		if err := noArg(); err != nil {
+			return err
+		}
+		toValue := func(f htmlx.Finder) any { return extract.Text(f.Node) }
+		if name == "html" {
+			toValue = func(f htmlx.Finder) any { return render(f.Node) }
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Map(mapValue(toValue))
+		}, values)
+
+	case name == "attr":
+		if err := needNodes(); err != nil {
+			return err
+		}
+		key, err := unquote(arg)
+		if err != nil || key == "" {
+			return errors.New("needs an attribute name")
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Filter(pred.AttrCond(key, func(string) bool { return true })).
+				Map(mapValue(func(f htmlx.Finder) any {
+					v, _ := f.Attr().Val(key)
+					return v
+				}))
+		}, values)
+
+	case name == "parent" || name == "children":
+		if err := noArg(); err != nil {
+			return err
+		}
+		split := func(f htmlx.Finder) htmlx.FinderStream { return f.Parent().StreamSelf() }
+		if name == "children" {
+			split = func(f htmlx.Finder) htmlx.FinderStream {
+				var cc []htmlx.Finder
+				for c := f.Node.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode {
+						cc = append(cc, htmlx.FinderFromNode(c))
+					}
+				}
+				return htmlx.Inject(cc)
+			}
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Join(split)
+		}, nodes)
+
+	case name == "filter":
+		if err := needNodes(); err != nil {
+			return err
+		}
+		sel, err := unquote(arg)
+		if err != nil {
+			return err
+		}
+		p, err := pred.CSS(sel)
+		if err != nil {
+			return err
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Filter(p)
+		}, nodes)
+
+	case name == "first" || name == "last" || name == "count":
+		if call {
+			return errors.New("takes no argument")
+		}
+		out := q.out
+		run := func(ff htmlx.FinderStream) htmlx.FinderStream { return ff.TakeN(1) }
+		switch name {
+		case "last":
+			run = func(ff htmlx.FinderStream) htmlx.FinderStream {
+				return ff.Last().StreamSelf()
+			}
+		case "count":
+			out = values
+			run = func(ff htmlx.FinderStream) htmlx.FinderStream {
+				return value(len(ff.Collect())).StreamSelf()
+			}
+		}
+		return push(run, out)
+
+	case name == "limit" || name == "skip":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return fmt.Errorf("needs a count, got %q", arg)
+		}
+		run := func(ff htmlx.FinderStream) htmlx.FinderStream { return ff.TakeN(n) }
+		if name == "skip" {
+			run = func(ff htmlx.FinderStream) htmlx.FinderStream { return ff.DropN(n) }
+		}
+		return push(run, q.out)
+
+	case strings.HasPrefix(src, "{"):
+		if err := needNodes(); err != nil {
+			return err
+		}
+		obj, err := compileObject(src)
+		if err != nil {
+			return err
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Map(mapValue(obj.eval))
+		}, values)
+
+	case name == "list":
+		if err := needNodes(); err != nil {
+			return err
+		}
+		sub, err := compile(arg)
+		if err != nil {
+			return err
+		}
+		return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+			return ff.Map(mapValue(func(f htmlx.Finder) any {
+				return sub.values(f)
+			}))
+		}, values)
+
+	case call:
+		return fmt.Errorf("unknown function %s", name)
+	}
+
+	if err := needNodes(); err != nil {
+		return err
+	}
+	sel, err := unquote(src)
+	if err != nil {
+		return err
+	}
+	p, err := pred.CSS(sel)
+	if err != nil {
+		return err
+	}
+	return push(func(ff htmlx.FinderStream) htmlx.FinderStream {
+		return ff.Join(descendants(p))
+	}, nodes)
+}
+
+// values runs the query on a single node, returning the JSON
+// of the results.
+func (q *query) values(f htmlx.Finder) []any {
+	res := []any{}
+	for r := range q.run(f.StreamSelf()) {
+		res = append(res, jsonOf(r.Node))
+	}
+	return res
+}
+
+type object struct {
+	keys []string
+	vals []*query
+}
+
+// compileObject parses {key: pipeline, ...}; a key alone, like {text},
+// means {text: text}.
+func compileObject(src string) (*object, error) {
+	if !strings.HasSuffix(src, "}") {
+		return nil, errors.New("missing }")
+	}
+	fields, err := split(src[1:len(src)-1], ',')
+	if err != nil {
+		return nil, err
+	}
+	obj := &object{}
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, pipe, ok := cutKey(field)
+		if !ok {
+			key, pipe = field, field
+		}
+		if key, err = unquote(strings.TrimSpace(key)); err != nil || key == "" {
+			return nil, fmt.Errorf("bad key in %q", field)
+		}
+		q, err := compile(pipe)
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		obj.vals = append(obj.vals, q)
+	}
+	return obj, nil
+}
+
+// cutKey splits key: pipeline at the first colon not in quotes;
+// the selector pseudo-classes, like li:first-child, are told apart
+// by a space after the key's colon.
+func cutKey(field string) (key, pipe string, ok bool) {
+	if strings.HasPrefix(field, `"`) {
+		end := strings.Index(field[1:], `"`)
+		if end < 0 {
+			return "", "", false
+		}
+		rest := strings.TrimSpace(field[end+2:])
+		if r, found := strings.CutPrefix(rest, ":"); found {
+			return field[:end+2], r, true
+		}
+		return "", "", false
+	}
+	i := strings.IndexByte(field, ':')
+	if i < 0 || !isIdent(strings.TrimSpace(field[:i])) ||
+		i+1 < len(field) && field[i+1] != ' ' {
+		return "", "", false
+	}
+	return field[:i], field[i+1:], true
+}
+
+func (o *object) eval(f htmlx.Finder) any {
+	m := make(extract.Object, len(o.keys))
+	for i, q := range o.vals {
+		var v any
+		if vv := q.values(f); len(vv) > 0 {
+			v = vv[0]
+		}
+		m[i] = extract.Member{Key: o.keys[i], Val: v}
+	}
+	return m
+}
+
+// descendants matches the nodes under f, but not f itself,
+// as in CSS.
+func descendants(p pred.Predicate) htmlx.SplitFunc {
+	return func(f htmlx.Finder) htmlx.FinderStream {
+		return f.FindAll(func(n *html.Node) bool { return n != f.Node && p(n) })
+	}
+}
+
+// mapValue maps a node into a raw node holding the JSON of a value.
+func mapValue(v func(htmlx.Finder) any) htmlx.MapFunc {
+	return func(f htmlx.Finder) {
+		*f.Node = *value(v(f)).Node
+	}
+}
+
+func value(v any) htmlx.Finder {
+	data, err := extract.Marshal(v)
+	if err != nil {
+		data, _ = extract.Marshal(err.Error())
+	}
+	return htmlx.FinderFromNode(&html.Node{Type: html.RawNode, Data: string(data)})
+}
+
+// jsonOf returns the value a raw node holds, or the HTML of a node.
+func jsonOf(n *html.Node) any {
+	if n.Type == html.RawNode {
+		return json.RawMessage(n.Data)
+	}
+	return render(n)
+}
+
+func render(n *html.Node) string {
+	var b strings.Builder
+	html.Render(&b, n)
+	return b.String()
+}
+
+// split cuts s at sep outside of quotes and brackets.
+func split(s string, sep byte) (parts []string, err error) {
+	var stack []byte
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, map[byte]byte{'(': ')', '[': ']', '{': '}'}[c])
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return nil, fmt.Errorf("unbalanced %c", c)
+			}
+			stack = stack[:len(stack)-1]
+		case c == sep && len(stack) == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c", quote)
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing %c", stack[len(stack)-1])
+	}
+	return append(parts, s[start:]), nil
+}
+
+// unquote returns a double-quoted string unquoted, and others as they are.
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `<ul id="menu">` +
+	`<li class="item"><a href="/a">One</a>` +
+	`<li class="item sel"><a href="/b">Two  <b>2</b></a>` +
+	`<li class="item"><span>Three</span>` +
+	`</ul>` +
+	`<svg><text x="1">label</text></svg>`
+
+func TestQuery(t *testing.T) {
+	tab := []struct {
+		query string
+		raw   bool
+		exp   string
+	}{
+		{"li | count", false, "3\n"},
+		{"a | text", false, "\"One\"\n\"Two 2\"\n"},
+		{"a | text", true, "One\nTwo 2\n"},
+		{`a | attr("href")`, true, "/a\n/b\n"},
+		{"li | attr(href)", true, ""},
+		{"b | parent | attr(href)", true, "/b\n"},
+		{"li | first | html", true, "<li class=\"item\"><a href=\"/a\">One</a></li>\n"},
+		{"li | last | text", true, "Three\n"},
+		{"li | skip(1) | limit(1) | text", true, "Two 2\n"},
+		{"li | filter(.sel) | children", true, "<a href=\"/b\">Two  <b>2</b></a>\n"},
+		{"li:nth-child(3) | text", true, "Three\n"},
+		{"b", false, "\"<b>2</b>\"\n"},
+		{"ul | children | count", false, "3\n"},
+		{"li | {url: a | attr(href), text}", false, "" +
+			"{\"url\":\"/a\",\"text\":\"One\"}\n" +
+			"{\"url\":\"/b\",\"text\":\"Two 2\"}\n" +
+			"{\"url\":null,\"text\":\"Three\"}\n"},
+		{`ul | {"all items": list(li | text), n: li | count}`, false,
+			"{\"all items\":[\"One\",\"Two 2\",\"Three\"],\"n\":3}\n"},
+		{"ul | . | li:first-child | text", true, "One\n"},
+		{"li | filter(.sel) | {a}", false,
+			"{\"a\":\"<a href=\\\"/b\\\">Two  <b>2</b></a>\"}\n"},
+		{"li | filter(.sel) | list(a)", false,
+			"[\"<a href=\\\"/b\\\">Two  <b>2</b></a>\"]\n"},
+		{`"html" | children | count`, false, "2\n"},
+		{`"body" | "text" | text`, true, "label\n"},
+		{`svg | {"text": "text" | text}`, false, "{\"text\":\"label\"}\n"},
+	}
+	for i, tc := range tab {
+		q, err := compile(tc.query)
+		if err != nil {
+			t.Errorf("tc[%d] %s: %v", i, tc.query, err)
+			continue
+		}
+		var b strings.Builder
+		err = run(&b, strings.NewReader(doc), "", config{query: q, raw: tc.raw, compact: true})
+		if err != nil {
+			t.Errorf("tc[%d] %s: %v", i, tc.query, err)
+			continue
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] %s mismatch:\ngot:\n%s\nexp:\n%s", i, tc.query, res, tc.exp)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tab := []struct {
+		query string
+		exp   string
+	}{
+		{"", "empty pipeline stage"},
+		{"li |", "empty pipeline stage"},
+		{"li | text | parent", "parent: needs nodes, got values"},
+		{"li | count | attr(x)", "attr(x): needs nodes, got values"},
+		{"li | attr()", "attr(): needs an attribute name"},
+		{"li | limit(x)", "limit(x): needs a count, got \"x\""},
+		{"li | text(1)", "text(1): takes no argument"},
+		{"li | bogus(1)", "bogus(1): unknown function bogus"},
+		{"li | {a: b", "missing }"},
+		{"li)", "unbalanced )"},
+		{`li | attr("x)`, "unterminated \""},
+		{"li >", "li >: "},
+	}
+	for i, tc := range tab {
+		_, err := compile(tc.query)
+		if err == nil {
+			t.Errorf("tc[%d] %s: no error", i, tc.query)
+			continue
+		}
+		res := err.Error()
+		if strings.HasSuffix(tc.exp, ": ") {
+			res = res[:min(len(res), len(tc.exp))]
+		}
+		if res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
@@ -3,11 +3,21 @@ package main
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/spf13/pflag"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/format"
+	"github.com/wkhere/htmlx/pp"
+	"github.com/wkhere/htmlx/pred"
+	"github.com/wkhere/htmlx/text"
 )
 
 func parseArgs(args []string) (c config, err error) {
+	if len(args) > 0 && args[0] == "serve" {
+		return parseServeArgs(args[1:])
+	}
 	var help bool
 
 	fs := pflag.NewFlagSet("parsehtml", pflag.ContinueOnError)
@@ -19,6 +29,124 @@ func parseArgs(args []string) (c config, err error) {
 	fs.BoolVar(&c.trimAttr, "trim-attr", true,
 		"don't print empty attributes")
 
+	fs.BoolVarP(&c.positions, "pos", "L", false,
+		"print source line:col of the nodes")
+
+	fs.StringVar(&c.format, "format", "text",
+		"pp output format: text, json, ndjson, dot or mermaid")
+
+	fs.BoolVar(&c.paths, "paths", false,
+		"add node paths to json and ndjson pp output")
+
+	fs.BoolVar(&c.skipSpaces, "skip-spaces", false,
+		"leave whitespace-only text out of pp output")
+
+	fs.BoolVar(&c.collapse, "collapse", false,
+		"print only the first of similar sibling elements in pp output")
+
+	fs.IntVar(&c.maxDepth, "max-depth", 0,
+		"limit the depth of pp output")
+
+	fs.IntVar(&c.maxChildren, "max-children", 0,
+		"limit the children printed per node in pp output")
+
+	fs.StringVar(&c.color, "color", "auto",
+		"color pp output: auto (if on a terminal), always or never")
+
+	fs.StringVarP(&c.output, "output", "o", "pp",
+		"output mode: pp (tree dump, see --format), html (rendered HTML),\n"+
+			"fmt (indented HTML), min (minified HTML), text (plain text),\n"+
+			"md (Markdown), ndjson (a record per selected element),\n"+
+			"links or tables")
+
+	fs.IntVar(&c.width, "width", 0,
+		"line width for text and fmt output (default 78 and 80),\n"+
+			"and pp output, truncated to the terminal width by default")
+
+	fs.StringVar(&c.indent, "indent", format.DefaultIndent,
+		"indentation for fmt output")
+
+	fs.BoolVar(&c.wrapAttrs, "wrap-attrs", false,
+		"put attributes on separate lines if a tag is too wide (fmt output)")
+
+	fs.BoolVarP(&c.inPlace, "write", "w", false,
+		"write html, fmt or min output back to the input files\n"+
+			"instead of stdout")
+
+	fs.StringVar(&c.links, "links", "footnotes",
+		"link style for text and md output: footnotes, inline\n"+
+			"or none (text only)")
+
+	fs.StringVarP(&c.css, "select", "s", "",
+		"print only the subtrees matching a CSS selector")
+
+	fs.StringVar(&c.id, "id", "",
+		"print only the element with this id")
+
+	fs.StringVar(&c.class, "class", "",
+		"print only the elements having this class")
+
+	fs.StringVar(&c.tag, "tag", "",
+		"print only the elements of this tag")
+
+	fs.StringVar(&c.text, "text", "",
+		"print only the elements with text containing this string")
+
+	fs.BoolVar(&c.count, "count", false,
+		"print the number of the selected elements instead")
+
+	fs.BoolVar(&c.first, "first", false,
+		"select only the first matching element")
+
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second,
+		"time limit for fetching a url, 0 for none")
+
+	fs.StringArrayVarP(&c.headers, "header", "H", nil,
+		"add a request header, like 'Accept-Language: en'")
+
+	fs.StringVarP(&c.userAgent, "user-agent", "A", "parsehtml",
+		"User-Agent request header")
+
+	fs.StringVar(&c.cookieFile, "cookies", "",
+		"send cookies from a file in the Netscape format")
+
+	fs.StringVarP(&c.user, "user", "u", "",
+		"basic auth user:password")
+
+	fs.StringVar(&c.bearer, "bearer", "",
+		"bearer auth token")
+
+	fs.IntVar(&c.maxRedirects, "max-redirects", 10,
+		"follow at most this many redirects, 0 for none")
+
+	fs.BoolVar(&c.ignoreStatus, "ignore-status", false,
+		"parse the response also when its status is not 2xx")
+
+	fs.BoolVar(&c.compressed, "compressed", true,
+		"ask for a gzip or brotli compressed response")
+
+	fs.BoolVarP(&c.insecure, "insecure", "k", false,
+		"don't verify the server's TLS certificate")
+
+	fs.StringVar(&c.charset, "charset", "",
+		"input encoding, like windows-1250; detected by default")
+
+	fs.StringArrayVar(&c.include, "include", nil,
+		"take the files matching this pattern from directories and archives,\n"+
+			"instead of *.html, *.htm and *.xhtml, also compressed")
+
+	fs.StringArrayVar(&c.exclude, "exclude", nil,
+		"skip the files matching this pattern in directories and archives")
+
+	fs.IntVarP(&c.jobs, "jobs", "j", 1,
+		"process this many inputs at once, keeping the output in order")
+
+	fs.BoolVar(&c.keepGoing, "keep-going", false,
+		"report a failed input and go on with the next ones")
+
+	fs.BoolVarP(&c.interactive, "interactive", "i", false,
+		"explore the input with commands, see help inside")
+
 	fs.BoolVarP(&help, "help", "h", false, "show this help and exit")
 
 	err = fs.Parse(args)
@@ -34,14 +162,89 @@ func parseArgs(args []string) (c config, err error) {
 			p("\tparsehtml [flags] http(s)://url")
 			p("\tparsehtml [flags] file://path")
 			p("\tparsehtml [flags] path")
+			p("\tparsehtml [flags] dir|archive.zip|archive.tar.gz")
+			p("\tparsehtml [flags] 'dir/**/*.html'")
+			p("\tparsehtml [flags] crawl.warc.gz|export.har|page.mhtml")
 			p("\tparsehtml [flags] - <file")
 			p("\tparsehtml [flags] one_input another_input")
+			p("\tparsehtml serve [flags]")
 			p("Flags:")
 			fs.PrintDefaults()
 		}
 		return c, nil
 	}
 
+	switch c.output {
+	case "pp", "html", "fmt", "min", "text", "md", "ndjson", "links", "tables":
+	default:
+		return c, fmt.Errorf("unknown output mode: %s", c.output)
+	}
+	switch c.format {
+	case "text":
+		c.ppFormat = pp.Text
+	case "json":
+		c.ppFormat = pp.JSON
+	case "ndjson":
+		c.ppFormat = pp.NDJSON
+	case "dot":
+		c.ppFormat = pp.DOT
+	case "mermaid":
+		c.ppFormat = pp.Mermaid
+	default:
+		return c, fmt.Errorf("unknown pp format: %s", c.format)
+	}
+	switch c.color {
+	case "auto", "always", "never":
+	default:
+		return c, fmt.Errorf("unknown color mode: %s", c.color)
+	}
+	switch {
+	case !c.inPlace:
+	case c.output == "html", c.output == "fmt", c.output == "min":
+	default:
+		return c, fmt.Errorf("-w works only with HTML output")
+	}
+
+	if c.charset != "" {
+		if _, err = (htmlx.Charset{Label: c.charset}).Name(); err != nil {
+			return c, err
+		}
+	}
+	if c.jobs < 1 {
+		return c, fmt.Errorf("--jobs must be at least 1")
+	}
+	if c.maxRedirects < 0 {
+		return c, fmt.Errorf("negative --max-redirects")
+	}
+
+	c.match, err = selector(c)
+	if err != nil {
+		return c, err
+	}
+	if (c.count || c.first) && c.match == nil {
+		return c, fmt.Errorf("--count and --first need a selection")
+	}
+	if c.inPlace && c.match != nil {
+		return c, fmt.Errorf("-w doesn't work with a selection")
+	}
+	if c.interactive && (c.inPlace || c.count || c.match != nil) {
+		return c, fmt.Errorf("-i doesn't work with -w or a selection")
+	}
+	if c.output == "ndjson" && c.match == nil {
+		c.match = pred.AnyElement()
+	}
+
+	switch c.links {
+	case "footnotes":
+		c.linkStyle = text.Footnotes
+	case "inline":
+		c.linkStyle = text.Inline
+	case "none":
+		c.linkStyle = text.NoLinks
+	default:
+		return c, fmt.Errorf("unknown link style: %s", c.links)
+	}
+
 	c.args = fs.Args()
 
 	if len(c.args) == 0 {
@@ -50,3 +253,64 @@ func parseArgs(args []string) (c config, err error) {
 
 	return c, nil
 }
+
+func parseServeArgs(args []string) (c config, err error) {
+	var help bool
+	c.serve = true
+
+	fs := pflag.NewFlagSet("parsehtml serve", pflag.ContinueOnError)
+	fs.SortFlags = false
+
+	fs.StringVar(&c.addr, "addr", "localhost:8040",
+		"address to listen on")
+
+	fs.IntVar(&c.cacheSize, "cache", 100,
+		"number of documents kept in memory")
+
+	fs.StringVar(&c.files, "files", ".",
+		"directory the document paths refer to")
+
+	fs.Int64Var(&c.maxSize, "max-size", 32<<20,
+		"largest document accepted, in bytes")
+
+	fs.StringVar(&c.charset, "charset", "",
+		"encoding of the documents, like windows-1250; detected by default")
+
+	fs.BoolVarP(&help, "help", "h", false, "show this help and exit")
+
+	err = fs.Parse(args)
+	if err != nil {
+		return c, err
+	}
+
+	if help {
+		c.help = func(w io.Writer) {
+			fs.SetOutput(w)
+			p := func(s string) { fmt.Fprintln(fs.Output(), s) }
+			p("Usage:")
+			p("\tparsehtml serve [flags]")
+			p("API:")
+			p("\tPOST   /docs             HTML body, or JSON {\"path\": \"file.html\"}")
+			p("\t                         -> {\"id\": ...}")
+			p("\tGET    /docs/{id}/query  ?sel=CSS selector&limit=N")
+			p("\t                         -> {\"count\": N, \"matches\": [{path, tag, attrs, text, html}]}")
+			p("\tDELETE /docs/{id}")
+			p("Flags:")
+			fs.PrintDefaults()
+		}
+		return c, nil
+	}
+
+	if fs.NArg() > 0 {
+		return c, fmt.Errorf("serve takes no arguments")
+	}
+	if c.cacheSize < 1 {
+		return c, fmt.Errorf("--cache must be at least 1")
+	}
+	if c.charset != "" {
+		if _, err = (htmlx.Charset{Label: c.charset}).Name(); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
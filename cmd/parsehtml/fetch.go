@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// fetcher gets the http(s) inputs.
+type fetcher struct {
+	client       *http.Client
+	header       http.Header
+	ignoreStatus bool
+	compressed   bool
+}
+
+func newFetcher(c config) (*fetcher, error) {
+	f := &fetcher{
+		header:       http.Header{},
+		ignoreStatus: c.ignoreStatus,
+		compressed:   c.compressed,
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if c.insecure {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	f.client = &http.Client{
+		Transport: tr,
+		Timeout:   c.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > c.maxRedirects {
+				if c.maxRedirects == 0 {
+					return http.ErrUseLastResponse
+				}
+				return fmt.Errorf("stopped after %d redirects", c.maxRedirects)
+			}
+			return nil
+		},
+	}
+
+	for _, h := range c.headers {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("bad header %q, want Name: value", h)
+		}
+		f.header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	if c.userAgent != "" && f.header.Get("User-Agent") == "" {
+		f.header.Set("User-Agent", c.userAgent)
+	}
+	switch {
+	case c.user != "" && c.bearer != "":
+		return nil, errors.New("--user and --bearer are exclusive")
+	case c.user != "":
+		u, p, _ := strings.Cut(c.user, ":")
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(u, p)
+		f.header.Set("Authorization", req.Header.Get("Authorization"))
+	case c.bearer != "":
+		f.header.Set("Authorization", "Bearer "+c.bearer)
+	}
+
+	if c.cookieFile != "" {
+		jar, err := loadCookies(c.cookieFile)
+		if err != nil {
+			return nil, err
+		}
+		f.client.Jar = jar
+	}
+	return f, nil
+}
+
+// get returns the response body, decompressed, and its Content-Type.
+func (f *fetcher) get(url string) (io.ReadCloser, string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, vv := range f.header {
+		req.Header[k] = vv
+	}
+	if f.compressed {
+		// Set explicitly, the transport leaves decoding to us.
+		req.Header.Set("Accept-Encoding", "gzip, br")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	ct := resp.Header.Get("Content-Type")
+	if !f.ignoreStatus && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	switch enc := strings.ToLower(resp.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+		return resp.Body, ct, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("GET %s: %w", url, err)
+		}
+		return readCloser{zr, resp.Body}, ct, nil
+	case "br":
+		return readCloser{brotli.NewReader(resp.Body), resp.Body}, ct, nil
+	default:
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("GET %s: unsupported content encoding %s", url, enc)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// loadCookies reads a cookie file in the Netscape format,
+// as written by curl and browser extensions.
+func loadCookies(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := bufio.NewScanner(file)
+	lineno := 0
+	for sc.Scan() {
+		lineno++
+		line := sc.Text()
+		httpOnly := false
+		if s, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = s, true
+		}
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		ff := strings.Split(line, "\t")
+		if len(ff) != 7 {
+			return nil, fmt.Errorf("%s:%d: want 7 tab-separated fields", path, lineno)
+		}
+		expires, err := strconv.ParseInt(ff[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad expiry: %w", path, lineno, err)
+		}
+
+		host := strings.TrimPrefix(ff[0], ".")
+		secure := strings.EqualFold(ff[3], "TRUE")
+		ck := &http.Cookie{
+			Name:     ff[5],
+			Value:    ff[6],
+			Path:     ff[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(ff[1], "TRUE") {
+			ck.Domain = host
+		}
+		if expires > 0 {
+			ck.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: ff[2]}, []*http.Cookie{ck})
+	}
+	return jar, sc.Err()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func testServer() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for _, ck := range r.Cookies() {
+			names = append(names, ck.Name+"="+ck.Value)
+		}
+		io.WriteString(w, strings.Join([]string{
+			r.Header.Get("User-Agent"),
+			r.Header.Get("X-Test"),
+			r.Header.Get("Authorization"),
+			strings.Join(names, ";"),
+		}, "|"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/echo", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		io.WriteString(zw, "zipped")
+		zw.Close()
+	})
+	mux.HandleFunc("/br", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		bw := brotli.NewWriter(w)
+		io.WriteString(bw, "brotli")
+		bw.Close()
+	})
+	return mux
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(testServer())
+	defer srv.Close()
+	tls := httptest.NewTLSServer(testServer())
+	defer tls.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	host = host[:strings.LastIndexByte(host, ':')]
+	cookies := filepath.Join(t.TempDir(), "cookies.txt")
+	err := os.WriteFile(cookies, []byte("# Netscape HTTP Cookie File\n"+
+		host+"\tFALSE\t/\tFALSE\t0\tsid\tabc\n"+
+		"#HttpOnly_"+host+"\tFALSE\t/\tFALSE\t0\tho\t1\n"+
+		host+"\tFALSE\t/\tFALSE\t1\told\tx\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := config{timeout: time.Second, userAgent: "parsehtml",
+		maxRedirects: 10, compressed: true}
+
+	tab := []struct {
+		url  string
+		conf func(*config)
+		exp  string
+		err  string
+	}{
+		{srv.URL + "/echo", nil, "parsehtml|||", ""},
+		{srv.URL + "/echo", func(c *config) {
+			c.headers = []string{"X-Test: 1", "User-Agent: other"}
+		}, "other|1||", ""},
+		{srv.URL + "/echo", func(c *config) { c.user = "u:p" },
+			"parsehtml||Basic dTpw|", ""},
+		{srv.URL + "/echo", func(c *config) { c.bearer = "tok" },
+			"parsehtml||Bearer tok|", ""},
+		{srv.URL + "/echo", func(c *config) { c.cookieFile = cookies },
+			"parsehtml|||sid=abc;ho=1", ""},
+		{srv.URL + "/missing", nil, "", "404 Not Found"},
+		{srv.URL + "/missing", func(c *config) { c.ignoreStatus = true }, "gone\n", ""},
+		{srv.URL + "/redirect", nil, "parsehtml|||", ""},
+		{srv.URL + "/redirect", func(c *config) { c.maxRedirects = 0 }, "", "302 Found"},
+		{srv.URL + "/slow", func(c *config) { c.timeout = 50 * time.Millisecond },
+			"", "Timeout"},
+		{srv.URL + "/gzip", nil, "zipped", ""},
+		{srv.URL + "/br", nil, "brotli", ""},
+		{tls.URL + "/echo", nil, "", "certificate"},
+		{tls.URL + "/echo", func(c *config) { c.insecure = true }, "parsehtml|||", ""},
+	}
+
+	for i, tc := range tab {
+		conf := base
+		if tc.conf != nil {
+			tc.conf(&conf)
+		}
+		f, err := newFetcher(conf)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+
+		var res bytes.Buffer
+		r, _, err := f.get(tc.url)
+		if err == nil {
+			_, err = io.Copy(&res, r)
+			r.Close()
+		}
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("tc[%d]: unexpected error: %v", i, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("tc[%d]: expected error containing %q, got %v", i, tc.err, err)
+		case res.String() != tc.exp:
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res.String(), tc.exp)
+		}
+	}
+}
+
+func TestFetcherErrors(t *testing.T) {
+	tab := []config{
+		{headers: []string{"no colon"}},
+		{user: "u:p", bearer: "tok"},
+		{cookieFile: "nonexistent"},
+	}
+	for i, tc := range tab {
+		if _, err := newFetcher(tc); err == nil {
+			t.Errorf("tc[%d]: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type result struct {
+	out  bytes.Buffer
+	save func() error
+	err  error
+}
+
+// run processes the inputs with conf.jobs workers, writing their output
+// in the order of the arguments, each under a header if there are many.
+// It stops at the first failed input unless conf.keepGoing is set:
+// no more inputs are taken and none after the failed one is written
+// back; the workers are done when it returns.
+// It returns the number of failed inputs.
+func run(w io.Writer, conf config, render renderFunc) (failed int) {
+	results := make([]chan *result, len(conf.inputs))
+	for i := range results {
+		results[i] = make(chan *result, 1)
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() { once.Do(func() { close(done) }) }
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range conf.inputs {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			select {
+			case next <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for range min(conf.jobs, len(conf.inputs)) {
+		wg.Go(func() {
+			for i := range next {
+				r := new(result)
+				r.save, r.err = process(&r.out, conf.inputs[i], conf, render)
+				failed := r.err != nil
+				results[i] <- r
+				if failed && !conf.keepGoing {
+					stop()
+					return
+				}
+			}
+		})
+	}
+	defer wg.Wait()
+	defer stop()
+
+	headers := len(conf.inputs) > 1 && !conf.count && !conf.inPlace &&
+		conf.output != "ndjson"
+
+	for i, ch := range results {
+		r := <-ch
+		if headers && (r.out.Len() > 0 || r.err == nil) {
+			if i > 0 {
+				io.WriteString(w, "\n")
+			}
+			fmt.Fprintf(w, "==> %s <==\n", conf.inputs[i].Name)
+		}
+		r.out.WriteTo(w)
+		if r.err == nil && r.save != nil {
+			r.err = r.save()
+		}
+
+		if r.err != nil {
+			failed++
+			err := r.err
+			if len(conf.inputs) > 1 {
+				err = fmt.Errorf("%s: %w", conf.inputs[i].Name, err)
+			}
+			fmt.Fprintln(os.Stderr, "parsehtml:", err)
+			if !conf.keepGoing {
+				return failed
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/pos"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	var args []string
+	var exp strings.Builder
+	for i := range 20 {
+		path := filepath.Join(dir, fmt.Sprintf("%d.html", i))
+		if i == 5 || i == 12 {
+			path += ".missing"
+		} else if err := os.WriteFile(path, fmt.Appendf(nil, "<p>%d", i), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, path)
+	}
+
+	tab := []struct {
+		keepGoing bool
+		failed    int
+		last      int
+	}{
+		{false, 1, 4},
+		{true, 2, 19},
+	}
+	for i, tc := range tab {
+		conf := config{output: "text", args: args, jobs: 4, keepGoing: tc.keepGoing}
+		conf.inputs = inputs(conf)
+		var b bytes.Buffer
+		failed := run(&b, conf, renderer(conf))
+		if failed != tc.failed {
+			t.Errorf("tc[%d]: failed %d, exp %d", i, failed, tc.failed)
+		}
+
+		exp.Reset()
+		for j := 0; j <= tc.last; j++ {
+			if j == 5 || j == 12 {
+				continue
+			}
+			if j > 0 {
+				exp.WriteString("\n")
+			}
+			fmt.Fprintf(&exp, "==> %s <==\n%d\n", args[j], j)
+		}
+		if res := b.String(); res != exp.String() {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, exp.String())
+		}
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	const src = "<p>x</p>"
+
+	tab := []struct {
+		jobs      int
+		keepGoing bool
+		processed int // -1 if any number of inputs may be taken
+	}{
+		{1, false, 5},
+		{4, false, -1},
+		{4, true, 19},
+	}
+	for i, tc := range tab {
+		dir := t.TempDir()
+		var args []string
+		for j := range 20 {
+			path := filepath.Join(dir, fmt.Sprintf("%02d.html", j))
+			if j == 5 {
+				path += ".missing"
+			} else if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, path)
+		}
+
+		conf := config{output: "html", inPlace: true, args: args, jobs: tc.jobs,
+			keepGoing: tc.keepGoing}
+		conf.inputs = inputs(conf)
+		var processed atomic.Int32
+		render := func(w io.Writer, n *html.Node, _ pos.Map) error {
+			processed.Add(1)
+			return html.Render(w, n)
+		}
+		if failed := run(io.Discard, conf, render); failed != 1 {
+			t.Errorf("tc[%d]: failed %d, exp 1", i, failed)
+		}
+		if n := int(processed.Load()); tc.processed >= 0 && n != tc.processed {
+			t.Errorf("tc[%d]: processed %d, exp %d", i, n, tc.processed)
+		}
+
+		// The workers are done: nothing gets written after run returns.
+		time.Sleep(10 * time.Millisecond)
+		for j, path := range args {
+			if j == 5 {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			written := string(data) != src
+			if exp := j < 5 || tc.keepGoing; written != exp {
+				t.Errorf("tc[%d] %s: written %v, exp %v", i, filepath.Base(path), written, exp)
+			}
+		}
+	}
+}
@@ -1,63 +1,264 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/format"
+	"github.com/wkhere/htmlx/input"
+	"github.com/wkhere/htmlx/md"
+	"github.com/wkhere/htmlx/minify"
+	"github.com/wkhere/htmlx/pos"
 	"github.com/wkhere/htmlx/pp"
+	"github.com/wkhere/htmlx/pred"
+	"github.com/wkhere/htmlx/text"
 	"golang.org/x/net/html"
 )
 
 type config struct {
 	compactSpaces bool
 	trimAttr      bool
+	positions     bool
+	format        string
+	ppFormat      pp.Format
+	paths         bool
+	skipSpaces    bool
+	collapse      bool
+	maxDepth      int
+	maxChildren   int
+	color         string
 
-	args []string
-	help func(io.Writer)
+	output    string
+	width     int
+	links     string
+	linkStyle text.LinkStyle
+	indent    string
+	wrapAttrs bool
+	inPlace   bool
+
+	css   string
+	id    string
+	class string
+	tag   string
+	text  string
+	count bool
+	first bool
+	match pred.Predicate
+
+	timeout      time.Duration
+	headers      []string
+	userAgent    string
+	cookieFile   string
+	user         string
+	bearer       string
+	maxRedirects int
+	ignoreStatus bool
+	compressed   bool
+	insecure     bool
+	fetch        *fetcher
+
+	charset string
+
+	jobs      int
+	keepGoing bool
+
+	interactive bool
+
+	serve     bool
+	addr      string
+	cacheSize int
+	files     string
+	maxSize   int64
+
+	include []string
+	exclude []string
+
+	args   []string
+	inputs []input.Input
+	help   func(io.Writer)
 }
 
-func process(url string, p *pp.Printer) (err error) {
+// renderFunc renders a tree, given the source positions of its
+// nodes with --pos.
+type renderFunc func(w io.Writer, root *html.Node, positions pos.Map) error
 
-	if !strings.Contains(url, "://") {
-		url = "file://" + url
+// process renders the input to w or, with -w, returns save writing
+// it back to its file, for the caller to decide if it should.
+func process(w io.Writer, in input.Input, conf config, render renderFunc) (save func() error, err error) {
+	root, positions, cs, err := load(in, conf)
+	if err != nil {
+		return nil, err
 	}
 
-	var r io.ReadCloser
-
-	switch tokens := strings.Split(url, "://"); tokens[0] {
-	case "file":
-		if tokens[1] == "-" {
-			r = os.Stdin
-			break
-		}
-		r, err = os.Open(tokens[1])
-		if err != nil {
-			return err
-		}
+	if conf.match != nil {
+		return nil, selectNodes(w, in.Name, root, positions, conf.match, conf, render)
+	}
+	if !conf.inPlace {
+		return nil, render(w, root, positions)
+	}
+	if in.Path == "" {
+		return nil, fmt.Errorf("can't write back to %s", in.Name)
+	}
+	return rewrite(in.Path, root, positions, cs, render)
+}
 
-	case "http", "https":
-		resp, err := http.Get(url)
-		if err != nil {
-			return err
-		}
-		r = resp.Body
+// load reads and parses the input, telling its charset and, with
+// --pos, the positions of the nodes.
+func load(in input.Input, conf config) (_ *html.Node, _ pos.Map, cs string, err error) {
+	var r io.ReadCloser
+	contentType := in.ContentType
 
+	switch proto, _, _ := strings.Cut(in.Name, "://"); {
+	case in.Open != nil:
+		r, err = in.Open()
+	case proto == "http", proto == "https":
+		r, contentType, err = conf.fetch.get(in.Name)
 	default:
-		return fmt.Errorf("unknown proto: %s", tokens[0])
+		return nil, nil, "", fmt.Errorf("unknown proto: %s", proto)
+	}
+	if err != nil {
+		return nil, nil, "", err
 	}
 	defer r.Close()
 
-	root, err := html.Parse(r)
+	src, cs, err := htmlx.Charset{ContentType: contentType, Label: conf.charset}.ReaderName(r)
 	if err != nil {
-		return err
+		return nil, nil, "", err
 	}
 
-	p.Print(os.Stdout, root)
+	if conf.positions {
+		root, positions, err := pos.Parse(src)
+		return root, positions, cs, err
+	}
+	root, err := html.Parse(src)
+	return root, nil, cs, err
+}
+
+// inputs expands the arguments; a local one failing to expand
+// becomes an input failing to open.
+func inputs(conf config) (list []input.Input) {
+	opt := input.Options{Include: conf.include, Exclude: conf.exclude}
+	for _, arg := range conf.args {
+		arg := strings.TrimPrefix(arg, "file://")
+		switch {
+		case arg == "-":
+			list = append(list, input.Input{Name: arg, Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(os.Stdin), nil
+			}})
+		case strings.Contains(arg, "://"):
+			list = append(list, input.Input{Name: arg})
+		default:
+			l, err := opt.List(arg)
+			if err != nil {
+				list = append(list, input.Input{Name: arg, Open: func() (io.ReadCloser, error) {
+					return nil, err
+				}})
+				continue
+			}
+			list = append(list, l...)
+		}
+	}
+	return list
+}
+
+// rewrite renders the file in its charset, cs, returning save
+// writing it back.
+func rewrite(path string, root *html.Node, positions pos.Map, cs string,
+	render renderFunc) (save func() error, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	w, err := htmlx.Charset{Label: cs}.Writer(&b)
+	if err != nil {
+		return nil, fmt.Errorf("can't write back: %w", err)
+	}
+	if err = render(w, root, positions); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return os.WriteFile(path, b.Bytes(), info.Mode().Perm())
+	}, nil
+}
 
-	return nil
+func renderer(conf config) renderFunc {
+	switch conf.output {
+	case "html":
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return html.Render(w, root)
+		}
+	case "min":
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return minify.Write(w, htmlx.FinderFromNode(root))
+		}
+	case "md":
+		c := md.Converter{}
+		if conf.linkStyle == text.Footnotes {
+			c.LinkStyle = md.Reference
+		}
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return c.Convert(w, htmlx.FinderFromNode(root))
+		}
+	case "ndjson":
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return writeRecord(w, "", root)
+		}
+	case "links":
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return writeLinks(w, root)
+		}
+	case "tables":
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return writeTables(w, root)
+		}
+	case "text":
+		r := text.Renderer{Width: conf.width, LinkStyle: conf.linkStyle}
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return r.Render(w, htmlx.FinderFromNode(root))
+		}
+	case "fmt":
+		f := format.Formatter{
+			Indent:    conf.indent,
+			Width:     conf.width,
+			WrapAttrs: conf.wrapAttrs,
+		}
+		return func(w io.Writer, root *html.Node, _ pos.Map) error {
+			return f.Format(w, htmlx.FinderFromNode(root))
+		}
+	default:
+		tty, cols := terminal(os.Stdout)
+		width := conf.width
+		if width == 0 && tty {
+			width = cols
+		}
+		p := pp.Printer{
+			CompactSpaces:   conf.compactSpaces,
+			TrimEmptyAttr:   conf.trimAttr,
+			Paths:           conf.paths,
+			Format:          conf.ppFormat,
+			SkipSpaces:      conf.skipSpaces,
+			MaxDepth:        conf.maxDepth,
+			MaxChildren:     conf.maxChildren,
+			CollapseSimilar: conf.collapse,
+			Color:           conf.color == "always" || conf.color == "auto" && tty && os.Getenv("NO_COLOR") == "",
+			Width:           width,
+		}
+		return func(w io.Writer, root *html.Node, positions pos.Map) error {
+			p := p
+			p.Positions = positions
+			p.Print(w, root)
+			return nil
+		}
+	}
 }
 
 func main() {
@@ -70,16 +271,37 @@ func main() {
 		os.Exit(0)
 	}
 
-	p := pp.Printer{
-		CompactSpaces: conf.compactSpaces,
-		TrimEmptyAttr: conf.trimAttr,
+	if conf.serve {
+		die(1, serve(conf))
 	}
 
-	for _, arg := range conf.args {
-		err = process(arg, &p)
+	conf.fetch, err = newFetcher(conf)
+	if err != nil {
+		die(2, err)
+	}
+	conf.inputs = inputs(conf)
+	if conf.interactive {
+		if len(conf.inputs) != 1 {
+			die(2, fmt.Errorf("-i needs a single input, got %d", len(conf.inputs)))
+		}
+		root, positions, _, err := load(conf.inputs[0], conf)
+		if err == nil {
+			err = interactive(root, positions, conf)
+		}
 		if err != nil {
 			die(1, err)
 		}
+		return
+	}
+	render := renderer(conf)
+
+	failed := run(os.Stdout, conf, render)
+	switch {
+	case failed == 0:
+	case conf.keepGoing && len(conf.inputs) > 1:
+		die(1, fmt.Errorf("%d of %d inputs failed", failed, len(conf.inputs)))
+	default:
+		os.Exit(1)
 	}
 }
 
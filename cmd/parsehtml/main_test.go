@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/pred"
+)
+
+func TestRewriteCharset(t *testing.T) {
+	tab := []struct {
+		data    string
+		charset string
+		raw     string // expected in the rewritten file
+		text    string // read back
+	}{
+		{`<meta charset="windows-1250"><p>Za` + "\xbf\xf3\xb3\xe6",
+			"", "Za\xbf\xf3\xb3\xe6", "Zażółć"},
+		{`<meta charset="windows-1250"><p>` + "\xb9 &#26085;",
+			"", "\xb9 &#26085;", "ą 日"},
+		{`<p>Za` + "\xbf\xf3\xb3\xe6", "windows-1250", "Za\xbf\xf3\xb3\xe6", ""},
+		{`<meta charset="utf-8"><p>Zażółć`, "", "Zażółć", "Zażółć"},
+	}
+	for i, tc := range tab {
+		path := filepath.Join(t.TempDir(), "a.html")
+		if err := os.WriteFile(path, []byte(tc.data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		conf := config{output: "fmt", inPlace: true, jobs: 1, args: []string{path}, charset: tc.charset}
+		conf.inputs = inputs(conf)
+		if failed := run(io.Discard, conf, renderer(conf)); failed != 0 {
+			t.Errorf("tc[%d]: failed", i)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(tc.raw)) {
+			t.Errorf("tc[%d] rewritten:\n%q\nexp to contain:\n%q", i, data, tc.raw)
+		}
+		if tc.text == "" {
+			continue
+		}
+		top, err := htmlx.FinderFromDataWithCharset(bytes.NewReader(data), htmlx.Charset{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := top.Find(pred.Element(atom.P))
+		if res := strings.TrimSpace(p.Find(pred.IsText()).Data); res != tc.text {
+			t.Errorf("tc[%d] read back mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.text)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/internal/extract"
+	"github.com/wkhere/htmlx/pp"
+)
+
+// record is an element written by the ndjson output, with the input
+// named if there are many.
+type record struct {
+	Input string            `json:"input,omitempty"`
+	Path  string            `json:"path"`
+	Tag   string            `json:"tag"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+	Text  string            `json:"text,omitempty"`
+}
+
+func writeRecord(w io.Writer, input string, n *html.Node) error {
+	return json.NewEncoder(w).Encode(newRecord(input, n))
+}
+
+func newRecord(input string, n *html.Node) record {
+	r := record{Input: input, Path: pp.Path(n), Tag: n.Data, Text: extract.Text(n)}
+	if n.Type != html.ElementNode {
+		r.Tag = ""
+	}
+	for _, a := range n.Attr {
+		if r.Attrs == nil {
+			r.Attrs = map[string]string{}
+		}
+		k := a.Key
+		if a.Namespace != "" {
+			k = a.Namespace + ":" + k
+		}
+		r.Attrs[k] = a.Val
+	}
+	return r
+}
+
+// writeLinks writes the href and text of each link, separated by a tab.
+func writeLinks(w io.Writer, root *html.Node) error {
+	var err error
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom != atom.A && n.DataAtom != atom.Area {
+			return true
+		}
+		href, ok := attr.L(n.Attr).Val("href")
+		if !ok {
+			return true
+		}
+		text := extract.Text(n)
+		if text == "" {
+			text, _ = attr.L(n.Attr).Val("alt")
+		}
+		if err == nil {
+			_, err = fmt.Fprintf(w, "%s\t%s\n", href, cell(text))
+		}
+		return false
+	})
+	return err
+}
+
+// writeTables writes each table as tab-separated rows,
+// with the tables separated by an empty line.
+func writeTables(w io.Writer, root *html.Node) error {
+	var err error
+	first := true
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom != atom.Table || n.Type != html.ElementNode {
+			return true
+		}
+		if !first && err == nil {
+			_, err = io.WriteString(w, "\n")
+		}
+		first = false
+		for _, row := range tableRows(n) {
+			if err == nil {
+				_, err = fmt.Fprintln(w, strings.Join(row, "\t"))
+			}
+		}
+		return true // nested tables follow
+	})
+	return err
+}
+
+// tableRows lays out the cells of a table on a grid; a cell spanning
+// several rows or columns is written in the first of them, leaving
+// the others empty.
+func tableRows(table *html.Node) (rows [][]string) {
+	var spans []int // the rows each column is still taken for
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				f(c)
+			case atom.Tr:
+				var row []string
+				col := 0
+				next := func() {
+					for col < len(spans) && spans[col] > 0 {
+						row = append(row, "")
+						col++
+					}
+				}
+				for td := c.FirstChild; td != nil; td = td.NextSibling {
+					if td.DataAtom != atom.Td && td.DataAtom != atom.Th {
+						continue
+					}
+					next()
+					rs, cs := span(td, "rowspan"), span(td, "colspan")
+					for k := range cs {
+						v := ""
+						if k == 0 {
+							v = extract.Text(td)
+						}
+						row = append(row, v)
+						if col >= len(spans) {
+							spans = append(spans, 0)
+						}
+						spans[col] = rs
+						col++
+					}
+				}
+				next()
+				for k := range spans {
+					if spans[k] > 0 {
+						spans[k]--
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	f(table)
+	return rows
+}
+
+// span returns the rowspan or colspan of a cell, 1 if unset or bad.
+func span(td *html.Node, key string) int {
+	v, _ := attr.L(td.Attr).Val(key)
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, 1000)
+}
+
+// walk visits the nodes in document order, descending into
+// the children of the nodes for which visit returns true.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+// cell keeps a value on one tab-separated line.
+func cell(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
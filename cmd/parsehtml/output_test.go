@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/text"
+)
+
+func TestTextOutput(t *testing.T) {
+	const doc = `<h1>T</h1><p><a href="/a">A</a> and <a href="http://b/">B</a> and <a>C</a></p>`
+
+	tab := []struct {
+		links text.LinkStyle
+		exp   string
+	}{
+		{text.Footnotes, "T\n=\n\nA[1] and B[2] and C\n\nReferences\n\n   1. /a\n   2. http://b/\n"},
+		{text.Inline, "T\n=\n\nA (/a) and B (http://b/) and C\n"},
+		{text.NoLinks, "T\n=\n\nA and B and C\n"},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		err = renderer(config{output: "text", linkStyle: tc.links})(&b, root, nil)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tab := []struct {
+		html string
+		exp  string
+	}{
+		{`<a href="http://e.com/">E</a>`, "http://e.com/\tE\n"},
+		{`<a href="../x?q=1#f">rel</a> <a href="#top">top</a>`, "../x?q=1#f\trel\n#top\ttop\n"},
+		{`<a name="anchor">no href</a><a>none</a>`, ""},
+		{`<a href="">empty</a>`, "\tempty\n"},
+		{`<a href="/i"><img alt="pic" src="p.png"></a>`, "/i\t\n"},
+		{`<map><area href="/m" alt="map area"></map>`, "/m\tmap area\n"},
+		{"<a href=\"/s\"> two\n\tlines <script>x</script></a>", "/s\ttwo lines\n"},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := writeLinks(&b, root); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
+
+func TestTables(t *testing.T) {
+	tab := []struct {
+		html string
+		exp  string
+	}{
+		{`<table><thead><tr><th>k</th><th>v</th></tr></thead>` +
+			`<tbody><tr><td>a</td><td>1</td></tr><tr><td>b` + "\t" + `c</td><td></td></tr></tbody></table>`,
+			"k\tv\na\t1\nb c\t\n"},
+		{`<table><tr><th colspan="2">head</th><th>x</th></tr>` +
+			`<tr><td>1</td><td>2</td><td>3</td></tr></table>`,
+			"head\t\tx\n1\t2\t3\n"},
+		{`<table><tr><td rowspan="2">r</td><td>1</td></tr>` +
+			`<tr><td>2</td></tr><tr><td>s</td><td>3</td></tr></table>`,
+			"r\t1\n\t2\ns\t3\n"},
+		{`<table><tr><td>a</td><td rowspan=2 colspan=2>big</td><td>b</td></tr>` +
+			`<tr><td>c</td><td>d</td></tr></table>`,
+			"a\tbig\t\tb\nc\t\t\td\n"},
+		{`<table><tr><td>a</td><td rowspan="3">end</td></tr><tr><td>b</td></tr></table>`,
+			"a\tend\nb\t\n"},
+		{`<table><tr><td colspan="x">bad</td><td>1</td></tr></table>`, "bad\t1\n"},
+		{`<table><tr><td>1</td></tr></table><p>x</p><table><tr><td>2</td></tr></table>`,
+			"1\n\n2\n"},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := writeTables(&b, root); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%q\nexp:\n%q", i, res, tc.exp)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tab := []struct {
+		html  string
+		input string
+		exp   string
+	}{
+		{`<p id="x" class="a b">one <b>two</b><script>no</script></p>`, "",
+			`{"path":"/html/body/p","tag":"p","attrs":{"class":"a b","id":"x"},"text":"one two"}`},
+		{`<p></p>`, "in.html", `{"input":"in.html","path":"/html/body/p","tag":"p"}`},
+		{`<svg><use xlink:href="#i"></use></svg>`, "",
+			`{"path":"/html/body/svg/use","tag":"use","attrs":{"xlink:href":"#i"}}`},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := root.FirstChild.LastChild.FirstChild
+		for n.FirstChild != nil && n.FirstChild.Type == html.ElementNode {
+			n = n.FirstChild
+		}
+		var b strings.Builder
+		if err := writeRecord(&b, tc.input, n); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp+"\n" {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
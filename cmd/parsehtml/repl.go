@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/term"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/extract"
+	"github.com/wkhere/htmlx/pos"
+	"github.com/wkhere/htmlx/pp"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// session is the state of the interactive mode: the current node
+// and the last list of nodes shown, which cd N picks from.
+type session struct {
+	conf      config
+	root      *html.Node
+	positions pos.Map // with --pos
+	cur       *html.Node
+	list      []*html.Node
+	words     []string // tags, .classes and #ids of the document
+}
+
+var commands = []struct{ name, args, help string }{
+	{"find", "SEL", "list the matches of a CSS selector under the current node"},
+	{"count", "SEL", "count the matches of a CSS selector"},
+	{"cd", "N|SEL|..|/", "go to a listed node, the first match, the parent or the root"},
+	{"parent", "", "go to the parent"},
+	{"next", "", "go to the next sibling element"},
+	{"prev", "", "go to the previous sibling element"},
+	{"children", "", "list the children, also as ls"},
+	{"pwd", "", "print the path of the current node"},
+	{"pp", "[N]", "print the current or a listed node as a tree"},
+	{"html", "[N]", "print as HTML"},
+	{"fmt", "[N]", "print as indented HTML"},
+	{"text", "[N]", "print as plain text"},
+	{"md", "[N]", "print as Markdown"},
+	{"help", "", "show this help"},
+	{"quit", "", "leave, also as exit or ^D"},
+}
+
+// maxList limits the nodes printed by find and children.
+const maxList = 50
+
+func newSession(root *html.Node, conf config) *session {
+	s := &session{conf: conf, root: root, cur: root}
+
+	seen := map[string]bool{}
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		add := func(w string) {
+			if !seen[w] {
+				seen[w] = true
+				s.words = append(s.words, w)
+			}
+		}
+		add(n.Data)
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "class":
+				for _, c := range strings.Fields(a.Val) {
+					add("." + c)
+				}
+			case "id":
+				if a.Val != "" {
+					add("#" + a.Val)
+				}
+			}
+		}
+		return true
+	})
+	slices.Sort(s.words)
+	return s
+}
+
+func (s *session) prompt() string {
+	return pp.Path(s.cur) + "> "
+}
+
+// exec runs a command line, telling if the session is over.
+func (s *session) exec(w io.Writer, line string) (quit bool, err error) {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch cmd {
+	case "":
+	case "quit", "exit":
+		return true, nil
+	case "help":
+		for _, c := range commands {
+			fmt.Fprintf(w, "  %-20s %s\n", c.name+" "+c.args, c.help)
+		}
+	case "find", "count":
+		if arg == "" {
+			return false, fmt.Errorf("%s needs a selector", cmd)
+		}
+		p, err := pred.CSS(arg)
+		if err != nil {
+			return false, err
+		}
+		found := s.find(p)
+		if cmd == "count" {
+			fmt.Fprintln(w, len(found))
+			break
+		}
+		s.show(w, found)
+		fmt.Fprintf(w, "%d matches\n", len(found))
+	case "cd":
+		return false, s.cd(w, arg)
+	case "parent":
+		if s.cur.Parent == nil {
+			return false, errors.New("at the root")
+		}
+		s.moveTo(w, s.cur.Parent)
+	case "next", "prev":
+		sib := func(n *html.Node) *html.Node { return n.NextSibling }
+		if cmd == "prev" {
+			sib = func(n *html.Node) *html.Node { return n.PrevSibling }
+		}
+		n := sib(s.cur)
+		for n != nil && n.Type != html.ElementNode {
+			n = sib(n)
+		}
+		if n == nil {
+			return false, fmt.Errorf("no %s sibling", cmd)
+		}
+		s.moveTo(w, n)
+	case "children", "ls":
+		var nn []*html.Node
+		for c := s.cur.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.TextNode || strings.TrimSpace(c.Data) != "" {
+				nn = append(nn, c)
+			}
+		}
+		s.show(w, nn)
+	case "pwd":
+		fmt.Fprintln(w, pp.Path(s.cur))
+	case "pp", "html", "fmt", "text", "md":
+		n, err := s.node(arg)
+		if err != nil {
+			return false, err
+		}
+		conf := s.conf
+		conf.output = cmd
+		if err := renderer(conf)(w, n, s.positions); err != nil {
+			return false, err
+		}
+		if cmd == "html" {
+			fmt.Fprintln(w)
+		}
+	default:
+		return false, fmt.Errorf("unknown command %s, try help", cmd)
+	}
+	return false, nil
+}
+
+func (s *session) find(p pred.Predicate) (nn []*html.Node) {
+	for f := range htmlx.FinderFromNode(s.cur).FindAll(p) {
+		nn = append(nn, f.Node)
+	}
+	return nn
+}
+
+// show lists the nodes for cd N.
+func (s *session) show(w io.Writer, nn []*html.Node) {
+	s.list = nn
+	for i, n := range nn {
+		if i == maxList {
+			fmt.Fprintf(w, "  ... %d more\n", len(nn)-maxList)
+			break
+		}
+		fmt.Fprintf(w, "%3d  %s  %s\n", i, pp.Path(n), short(n))
+	}
+}
+
+func (s *session) moveTo(w io.Writer, n *html.Node) {
+	s.cur = n
+	fmt.Fprintln(w, short(n))
+}
+
+func (s *session) cd(w io.Writer, arg string) error {
+	switch arg {
+	case "", "/":
+		s.moveTo(w, s.root)
+		return nil
+	case "..":
+		if s.cur.Parent == nil {
+			return errors.New("at the root")
+		}
+		s.moveTo(w, s.cur.Parent)
+		return nil
+	}
+	if _, err := strconv.Atoi(arg); err == nil {
+		n, err := s.node(arg)
+		if err != nil {
+			return err
+		}
+		s.moveTo(w, n)
+		return nil
+	}
+	p, err := pred.CSS(arg)
+	if err != nil {
+		return err
+	}
+	found := s.find(p)
+	if len(found) == 0 {
+		return fmt.Errorf("no match for %s", arg)
+	}
+	s.moveTo(w, found[0])
+	return nil
+}
+
+// node returns the current node, or a listed one.
+func (s *session) node(arg string) (*html.Node, error) {
+	if arg == "" {
+		return s.cur, nil
+	}
+	i, err := strconv.Atoi(arg)
+	if err != nil || i < 0 || i >= len(s.list) {
+		return nil, fmt.Errorf("no node %s listed", arg)
+	}
+	return s.list[i], nil
+}
+
+// short describes a node in a line, like <div id="main" class="a b">.
+func short(n *html.Node) string {
+	const max = 40
+	switch n.Type {
+	case html.ElementNode:
+		var b strings.Builder
+		b.WriteString("<" + n.Data)
+		for _, a := range n.Attr {
+			if a.Key == "id" || a.Key == "class" {
+				fmt.Fprintf(&b, " %s=%q", a.Key, a.Val)
+			}
+		}
+		b.WriteString(">")
+		if t := extract.Text(n); t != "" {
+			b.WriteString(" " + strconv.Quote(trunc(t, max)))
+		}
+		return b.String()
+	case html.TextNode:
+		return strconv.Quote(trunc(strings.Join(strings.Fields(n.Data), " "), max))
+	case html.CommentNode:
+		return "<!--" + trunc(n.Data, max) + "-->"
+	case html.DocumentNode:
+		return "document"
+	case html.DoctypeNode:
+		return "<!DOCTYPE " + n.Data + ">"
+	}
+	return n.Data
+}
+
+func trunc(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+// complete is the tab completion of the terminal: the command names
+// in the first word, then the tags, classes and ids of the document.
+func (s *session) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	start := strings.LastIndexAny(line[:pos], " >+~,(") + 1
+	frag := line[start:pos]
+
+	var cands []string
+	if strings.TrimSpace(line[:start]) == "" {
+		for _, c := range commands {
+			cands = append(cands, c.name)
+		}
+	} else {
+		if i := strings.LastIndexAny(frag, ".#"); i >= 0 {
+			start += i
+			frag = frag[i:]
+		}
+		cands = s.words
+	}
+
+	var match []string
+	for _, c := range cands {
+		if strings.HasPrefix(c, frag) {
+			match = append(match, c)
+		}
+	}
+	if len(match) == 0 {
+		return line, pos, true
+	}
+	common := match[0]
+	for _, m := range match[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(match) == 1 && start == 0 {
+		common += " "
+	}
+	return line[:start] + common + line[pos:], start + len(common), true
+}
+
+// interactive runs a session on the terminal, or reading the commands
+// from stdin if it is not one.
+func interactive(root *html.Node, positions pos.Map, conf config) error {
+	s := newSession(root, conf)
+	s.positions = positions
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			quit, err := s.exec(os.Stdout, sc.Text())
+			if err != nil {
+				fmt.Fprintln(os.Stdout, "error:", err)
+			}
+			if quit {
+				break
+			}
+		}
+		return sc.Err()
+	}
+
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, old)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, s.prompt())
+	t.AutoCompleteCallback = s.complete
+	if home, err := os.UserHomeDir(); err == nil {
+		h := openHistory(filepath.Join(home, ".parsehtml_history"))
+		defer h.close()
+		t.History = h
+	}
+	if w, h, err := term.GetSize(fd); err == nil {
+		t.SetSize(w, h)
+	}
+
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		quit, err := s.exec(t, line)
+		if err != nil {
+			fmt.Fprintln(t, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+		t.SetPrompt(s.prompt())
+	}
+}
+
+// history keeps the lines read by the terminal in a file,
+// between the sessions.
+type history struct {
+	lines []string
+	file  *os.File
+}
+
+const maxHistory = 1000
+
+func openHistory(path string) *history {
+	h := &history{}
+	if data, err := os.ReadFile(path); err == nil {
+		h.lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		if len(h.lines) == 1 && h.lines[0] == "" {
+			h.lines = nil
+		}
+		h.lines = h.lines[max(len(h.lines)-maxHistory, 0):]
+	}
+	h.file, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	return h
+}
+
+func (h *history) Add(entry string) {
+	if entry == "" || len(h.lines) > 0 && h.lines[len(h.lines)-1] == entry {
+		return
+	}
+	h.lines = append(h.lines, entry)
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[1:]
+	}
+	if h.file != nil {
+		fmt.Fprintln(h.file, entry)
+	}
+}
+
+func (h *history) Len() int { return len(h.lines) }
+
+func (h *history) At(i int) string { return h.lines[len(h.lines)-1-i] }
+
+func (h *history) close() {
+	if h.file != nil {
+		h.file.Close()
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSession(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(
+		`<ul id="l"><li class="a">one<li class="a b">two<li>three</ul><p>x</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSession(doc, config{output: "pp", trimAttr: true})
+
+	tab := []struct {
+		cmd string
+		exp string
+	}{
+		{"count li", "3\n"},
+		{"find li.a", "" +
+			"  0  /html/body/ul/li[1]  <li class=\"a\"> \"one\"\n" +
+			"  1  /html/body/ul/li[2]  <li class=\"a b\"> \"two\"\n" +
+			"2 matches\n"},
+		{"cd 1", "<li class=\"a b\"> \"two\"\n"},
+		{"next", "<li> \"three\"\n"},
+		{"prev", "<li class=\"a b\"> \"two\"\n"},
+		{"text", "two\n"},
+		{"parent", "<ul id=\"l\"> \"one two three\"\n"},
+		{"pwd", "/html/body/ul\n"},
+		{"cd ..", "<body> \"one two three x\"\n"},
+		{"children", "" +
+			"  0  /html/body/ul  <ul id=\"l\"> \"one two three\"\n" +
+			"  1  /html/body/p  <p> \"x\"\n"},
+		{"html 1", "<p>x</p>\n"},
+		{"pp 1", "T:ELEM D:`p`\n  T:TEXT D:`x`\n"},
+		{"cd p", "<p> \"x\"\n"},
+		{"cd /", "document\n"},
+		{"next", "error: no next sibling\n"},
+		{"cd 7", "error: no node 7 listed\n"},
+		{"find ul >", "error: "},
+		{"bogus", "error: unknown command bogus, try help\n"},
+	}
+	for i, tc := range tab {
+		var b strings.Builder
+		if _, err := s.exec(&b, tc.cmd); err != nil {
+			b.WriteString("error: " + err.Error() + "\n")
+		}
+		res := b.String()
+		if strings.HasSuffix(tc.exp, ": ") {
+			res = res[:min(len(res), len(tc.exp))]
+		}
+		if res != tc.exp {
+			t.Errorf("tc[%d] %s mismatch:\ngot:\n%s\nexp:\n%s", i, tc.cmd, res, tc.exp)
+		}
+	}
+
+	if quit, _ := s.exec(new(strings.Builder), "quit"); !quit {
+		t.Error("quit expected")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(
+		`<div id="main" class="item items"><span class="other">x</span></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSession(doc, config{})
+
+	tab := []struct {
+		line string
+		exp  string
+	}{
+		{"fi", "find "},
+		{"c", "c"},
+		{"co", "count "},
+		{"find sp", "find span"},
+		{"find div.it", "find div.item"},
+		{"find div > .o", "find div > .other"},
+		{"find #m", "find #main"},
+		{"find zz", "find zz"},
+	}
+	for i, tc := range tab {
+		res, pos, ok := s.complete(tc.line, len(tc.line), '\t')
+		if !ok || res != tc.exp || pos != len(res) {
+			t.Errorf("tc[%d] mismatch: got %q at %d, exp %q", i, res, pos, tc.exp)
+		}
+	}
+	if _, _, ok := s.complete("x", 1, 'a'); ok {
+		t.Error("only tab expected to complete")
+	}
+}
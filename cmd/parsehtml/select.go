@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/pos"
+	"github.com/wkhere/htmlx/pp"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// selector builds the predicate of the selection flags, all of which
+// have to match; nil means no selection.
+func selector(c config) (pred.Predicate, error) {
+	var ps []pred.Predicate
+
+	if c.css != "" {
+		p, err := pred.CSS(c.css)
+		if err != nil {
+			return nil, fmt.Errorf("bad selector: %w", err)
+		}
+		ps = append(ps, p)
+	}
+	if c.id != "" {
+		ps = append(ps, pred.ID(c.id))
+	}
+	if c.class != "" {
+		ps = append(ps, pred.Class(c.class))
+	}
+	if c.tag != "" {
+		ps = append(ps, tag(c.tag))
+	}
+	if c.text != "" {
+		ps = append(ps, hasText(c.text))
+	}
+
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	return func(h *html.Node) bool {
+		for _, p := range ps {
+			if !p(h) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func tag(name string) pred.Predicate {
+	name = strings.ToLower(name)
+	if a := atom.Lookup([]byte(name)); a != 0 {
+		return pred.Element(a)
+	}
+	return func(h *html.Node) bool {
+		return h.Type == html.ElementNode && h.Data == name
+	}
+}
+
+// hasText matches elements with a text child containing s.
+func hasText(s string) pred.Predicate {
+	return func(h *html.Node) bool {
+		if h.Type != html.ElementNode {
+			return false
+		}
+		for c := h.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode && strings.Contains(c.Data, s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// selectNodes renders the subtrees matching p, each after a separator
+// line with its path, or only their count.
+func selectNodes(w io.Writer, name string, root *html.Node, positions pos.Map,
+	p pred.Predicate, conf config, render renderFunc) error {
+
+	top := htmlx.FinderFromNode(root)
+	var found []htmlx.Finder
+	if conf.first {
+		if f := top.Find(p); !f.IsEmpty() {
+			found = append(found, f)
+		}
+	} else {
+		found = top.FindAll(p).Collect()
+	}
+
+	if conf.count {
+		if len(conf.inputs) > 1 {
+			fmt.Fprintf(w, "%s: ", name)
+		}
+		_, err := fmt.Fprintln(w, len(found))
+		return err
+	}
+
+	for _, f := range found {
+		if conf.output == "ndjson" {
+			input := ""
+			if len(conf.inputs) > 1 {
+				input = name
+			}
+			if err := writeRecord(w, input, f.Node); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "--- %s\n", pp.Path(f.Node)); err != nil {
+			return err
+		}
+		if err := render(w, f.Node, positions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/input"
+	"github.com/wkhere/htmlx/pos"
+)
+
+func TestSelectNodes(t *testing.T) {
+	const doc = `<div id="m" class="box"><p class="a">one</p><p class="a b">two <i>x</i></p>` +
+		`<my-tag>three</my-tag></div><p>two</p>`
+
+	one := []input.Input{{Name: "a.html"}}
+	two := []input.Input{{Name: "a.html"}, {Name: "b.html"}}
+
+	tab := []struct {
+		conf config
+		exp  string
+	}{
+		{config{css: "p.a"},
+			"--- /html/body/div/p[1]\n<p class=\"a\">one</p>\n" +
+				"--- /html/body/div/p[2]\n<p class=\"a b\">two <i>x</i></p>\n"},
+		{config{css: "p.a", first: true},
+			"--- /html/body/div/p[1]\n<p class=\"a\">one</p>\n"},
+		{config{css: "p", count: true}, "3\n"},
+		{config{css: "p", count: true, inputs: one}, "3\n"},
+		{config{css: "p", count: true, inputs: two}, "a.html: 3\n"},
+		{config{css: "p", first: true, count: true}, "1\n"},
+		{config{css: "table", count: true}, "0\n"},
+		{config{css: "table", first: true}, ""},
+		{config{id: "m", count: true}, "1\n"},
+		{config{class: "b"},
+			"--- /html/body/div/p[2]\n<p class=\"a b\">two <i>x</i></p>\n"},
+		{config{tag: "P", class: "a", count: true}, "2\n"},
+		{config{tag: "my-tag"}, "--- /html/body/div/my-tag\n<my-tag>three</my-tag>\n"},
+		{config{text: "two"},
+			"--- /html/body/div/p[2]\n<p class=\"a b\">two <i>x</i></p>\n" +
+				"--- /html/body/p\n<p>two</p>\n"},
+		{config{text: "two", css: "div p"},
+			"--- /html/body/div/p[2]\n<p class=\"a b\">two <i>x</i></p>\n"},
+		{config{text: "x", tag: "p", count: true}, "0\n"},
+		{config{css: "i", output: "ndjson", inputs: two},
+			`{"input":"a.html","path":"/html/body/div/p[2]/i","tag":"i","text":"x"}` + "\n"},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := selector(tc.conf)
+		if err != nil || p == nil {
+			t.Errorf("tc[%d]: selector %v", i, err)
+			continue
+		}
+		var b strings.Builder
+		err = selectNodes(&b, "a.html", root, nil, p, tc.conf, func(w io.Writer, n *html.Node, _ pos.Map) error {
+			if err := html.Render(w, n); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "\n")
+			return err
+		})
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	if p, err := selector(config{}); p != nil || err != nil {
+		t.Errorf("no flags: got %v, %v", p != nil, err)
+	}
+	if _, err := selector(config{css: "p >"}); err == nil ||
+		!strings.HasPrefix(err.Error(), "bad selector: ") {
+		t.Errorf("bad css: got %v", err)
+	}
+}
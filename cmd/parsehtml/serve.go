@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/input"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// server is the HTTP/JSON API of parsehtml serve:
+//
+//	POST   /docs               an HTML body, or {"path": "file.html"} as JSON
+//	GET    /docs/{id}/query    ?sel=CSS selector&limit=N
+//	DELETE /docs/{id}
+//
+// The documents are kept in memory, the least recently used ones
+// evicted; the ids are hashes of the content, so posting the same
+// document again gives the same id.
+type server struct {
+	docs    *lru
+	files   string // the directory the paths refer to
+	maxSize int64
+	charset string
+}
+
+// errTooLarge is the error of a file over the maximum document size.
+var errTooLarge = errors.New("document too large")
+
+type docInfo struct {
+	ID string `json:"id"`
+}
+
+type queryResult struct {
+	Count   int     `json:"count"`
+	Matches []match `json:"matches"`
+}
+
+// match is a query result: a record, with the HTML of the element.
+type match struct {
+	record
+	HTML string `json:"html"`
+}
+
+func newServer(c config) *server {
+	return &server{
+		docs:    newLRU(c.cacheSize),
+		files:   c.files,
+		maxSize: c.maxSize,
+		charset: c.charset,
+	}
+}
+
+func serve(c config) error {
+	log.Printf("parsehtml: serving on http://%s", c.addr)
+	return http.ListenAndServe(c.addr, newServer(c).handler())
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /docs", s.post)
+	mux.HandleFunc("GET /docs/{id}/query", s.query)
+	mux.HandleFunc("DELETE /docs/{id}", s.delete)
+	return mux
+}
+
+func (s *server) post(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxSize))
+	if err != nil {
+		code := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		fail(w, code, err)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if mt, _, _ := mime.ParseMediaType(contentType); mt == "application/json" {
+		var ref struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(body, &ref); err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+		if body, contentType, err = s.file(ref.Path); err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, errTooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			fail(w, code, err)
+			return
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	id := hex.EncodeToString(sum[:8])
+	if s.docs.get(id) == nil {
+		cs := htmlx.Charset{ContentType: contentType, Label: s.charset}
+		doc, err := htmlx.FinderFromDataWithCharset(bytes.NewReader(body), cs)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+		s.docs.put(id, doc.Node)
+	}
+	reply(w, http.StatusCreated, docInfo{ID: id})
+}
+
+// file reads a document the path refers to, within the files directory.
+func (s *server) file(path string) ([]byte, string, error) {
+	if !filepath.IsLocal(path) {
+		return nil, "", fmt.Errorf("path must be relative to the files directory: %q", path)
+	}
+	list, err := input.Options{}.List(filepath.Join(s.files, path))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) != 1 {
+		return nil, "", fmt.Errorf("%s holds %d documents, not one", path, len(list))
+	}
+	rc, err := list[0].Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, s.maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, "", fmt.Errorf("%s: %w, over %d bytes", path, errTooLarge, s.maxSize)
+	}
+	return data, list[0].ContentType, nil
+}
+
+func (s *server) query(w http.ResponseWriter, r *http.Request) {
+	root := s.docs.get(r.PathValue("id"))
+	if root == nil {
+		fail(w, http.StatusNotFound, errors.New("no such document"))
+		return
+	}
+	sel := r.URL.Query().Get("sel")
+	if sel == "" {
+		fail(w, http.StatusBadRequest, errors.New("sel parameter missing"))
+		return
+	}
+	p, err := pred.CSS(sel)
+	if err != nil {
+		fail(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := -1
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			fail(w, http.StatusBadRequest, fmt.Errorf("bad limit: %q", l))
+			return
+		}
+	}
+
+	res := queryResult{Matches: []match{}}
+	for f := range htmlx.FinderFromNode(root).FindAll(p) {
+		res.Count++
+		if limit >= 0 && len(res.Matches) >= limit {
+			continue
+		}
+		var b strings.Builder
+		html.Render(&b, f.Node)
+		res.Matches = append(res.Matches, match{newRecord("", f.Node), b.String()})
+	}
+	reply(w, http.StatusOK, res)
+}
+
+func (s *server) delete(w http.ResponseWriter, r *http.Request) {
+	if !s.docs.remove(r.PathValue("id")) {
+		fail(w, http.StatusNotFound, errors.New("no such document"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func reply(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func fail(w http.ResponseWriter, code int, err error) {
+	reply(w, code, map[string]string{"error": err.Error()})
+}
+
+// lru is a cache of parsed documents holding at most size of them.
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *lruEntry, the most recent first
+	byID  map[string]*list.Element
+}
+
+type lruEntry struct {
+	id  string
+	doc *html.Node
+}
+
+func newLRU(size int) *lru {
+	return &lru{size: size, order: list.New(), byID: map[string]*list.Element{}}
+}
+
+func (c *lru) get(id string) *html.Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.byID[id]
+	if e == nil {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).doc
+}
+
+func (c *lru) put(id string, doc *html.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.byID[id]; e != nil {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.byID[id] = c.order.PushFront(&lruEntry{id, doc})
+	for c.order.Len() > c.size {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.byID, e.Value.(*lruEntry).id)
+	}
+}
+
+func (c *lru) remove(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.byID[id]
+	if e == nil {
+		return false
+	}
+	c.order.Remove(e)
+	delete(c.byID, id)
+	return true
+}
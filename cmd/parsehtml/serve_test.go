@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "page.html"),
+		[]byte(`<ul><li class="a" id="x">one<li class="a">two</ul>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "big.html"), []byte(strings.Repeat("x", 1001)), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(config{cacheSize: 2, files: dir, maxSize: 1000}).handler())
+	defer srv.Close()
+
+	do := func(method, path, contentType, body string, v any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+	post := func(contentType, body string) (string, int) {
+		var info docInfo
+		code := do("POST", "/docs", contentType, body, &info)
+		return info.ID, code
+	}
+	query := func(id, sel, limit string) (res queryResult, code int) {
+		q := url.Values{"sel": {sel}}
+		if limit != "" {
+			q.Set("limit", limit)
+		}
+		code = do("GET", "/docs/"+id+"/query?"+q.Encode(), "", "", &res)
+		return res, code
+	}
+
+	id, code := post("text/html", `<p class="x">a <b>b</b></p><p>c</p>`)
+	if code != http.StatusCreated || id == "" {
+		t.Fatalf("post: %d %q", code, id)
+	}
+	if id2, _ := post("text/html", `<p class="x">a <b>b</b></p><p>c</p>`); id2 != id {
+		t.Errorf("same document, different id: %s %s", id, id2)
+	}
+
+	res, code := query(id, "p", "1")
+	if code != http.StatusOK || res.Count != 2 || len(res.Matches) != 1 {
+		t.Fatalf("query: %d %+v", code, res)
+	}
+	m := res.Matches[0]
+	if m.Path != "/html/body/p[1]" || m.Tag != "p" || m.Text != "a b" ||
+		m.Attrs["class"] != "x" || m.HTML != `<p class="x">a <b>b</b></p>` {
+		t.Errorf("match mismatch: %+v", m)
+	}
+
+	fid, code := post("application/json", `{"path": "page.html"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("post path: %d", code)
+	}
+	if res, _ := query(fid, "li.a", ""); res.Count != 2 || res.Matches[1].Text != "two" {
+		t.Errorf("query file: %+v", res)
+	}
+
+	// The first document is the least recently used one.
+	post("text/html", "<p>third")
+	if _, code := query(id, "p", ""); code != http.StatusNotFound {
+		t.Errorf("evicted document: %d", code)
+	}
+
+	if code := do("DELETE", "/docs/"+fid, "", "", nil); code != http.StatusNoContent {
+		t.Errorf("delete: %d", code)
+	}
+	if code := do("DELETE", "/docs/"+fid, "", "", nil); code != http.StatusNotFound {
+		t.Errorf("delete again: %d", code)
+	}
+
+	nid, _ := post("text/html", "<p>x")
+	errs := []struct {
+		method, path, contentType, body string
+		code                            int
+	}{
+		{"POST", "/docs", "application/json", `{"path": "../etc/passwd"}`, 400},
+		{"POST", "/docs", "application/json", `{"path": "missing.html"}`, 400},
+		{"POST", "/docs", "application/json", `{`, 400},
+		{"POST", "/docs", "text/html", strings.Repeat("x", 1001), 413},
+		{"POST", "/docs", "application/json", `{"path": "big.html"}`, 413},
+		{"GET", "/docs/nope/query?sel=p", "", "", 404},
+		{"GET", "/docs/" + fid + "/query", "", "", 404},
+		{"GET", "/docs/" + nid + "/query", "", "", 400},
+		{"GET", "/docs/" + nid + "/query?sel=p+>", "", "", 400},
+		{"GET", "/docs/" + nid + "/query?sel=p&limit=-1", "", "", 400},
+	}
+	for i, tc := range errs {
+		var e map[string]string
+		code := do(tc.method, tc.path, tc.contentType, tc.body, &e)
+		if code != tc.code || e["error"] == "" {
+			t.Errorf("tc[%d]: got %d %v, exp %d", i, code, e, tc.code)
+		}
+	}
+}
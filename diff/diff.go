@@ -0,0 +1,231 @@
+// Package diff computes structural differences between two html.Node
+// trees: elements and text added, removed or moved, and changes of
+// text and attributes of the nodes present in both.
+//
+// Nodes are matched GumTree-style. First identical subtrees are paired,
+// then elements with the same id, then containers sharing most of their
+// matched descendants, and at last the remaining children of matched
+// parents, by tag and class. Whitespace-only text and comments are not
+// compared, and whitespace in text is collapsed.
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx"
+)
+
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Moved
+	TextChanged
+	AttrChanged
+)
+
+var kindNames = [...]string{"added", "removed", "moved", "text", "attr"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return kindNames[k]
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Change is a single edit turning the old tree into the new one.
+//
+// Paths are XPath-like, e.g. /html/body/div[2]/text(), with indexes
+// counted among the compared siblings of the same name.
+type Change struct {
+	Kind Kind `json:"kind"`
+
+	// Path is the location in the old tree; empty for Added.
+	Path string `json:"path,omitempty"`
+
+	// NewPath is the location in the new tree; empty for Removed.
+	NewPath string `json:"newPath,omitempty"`
+
+	// Attr is the attribute name for AttrChanged.
+	Attr string `json:"attr,omitempty"`
+
+	// Old and New are the changed text or attribute values, empty when
+	// the attribute is absent. For Added and Removed they summarize
+	// the node: its start tag or quoted text.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+
+	OldNode *html.Node `json:"-"`
+	NewNode *html.Node `json:"-"`
+}
+
+type Diff struct {
+	// Changes are the removals, in the old document order,
+	// followed by the other changes, in the new document order.
+	// Only the topmost node of an added or removed subtree is reported.
+	Changes []Change
+
+	old, new *tree
+}
+
+// Compare computes the changes from the subtree a to the subtree b.
+// The root nodes are always considered matching.
+func Compare(a, b htmlx.Finder) *Diff {
+	d := &Diff{old: newTree(a.Node), new: newTree(b.Node)}
+	match(d.old, d.new)
+	d.Changes = script(d.old, d.new)
+	return d
+}
+
+func script(a, b *tree) []Change {
+	cc := []Change{}
+
+	for _, x := range a.nodes {
+		if x.match == nil && (x.parent == nil || x.parent.match != nil) {
+			cc = append(cc, Change{
+				Kind: Removed, Path: x.path, Old: summary(x), OldNode: x.n,
+			})
+		}
+	}
+
+	moved := reordered(b)
+	for _, y := range b.nodes {
+		x := y.match
+		if x == nil {
+			if y.parent == nil || y.parent.match != nil {
+				cc = append(cc, Change{
+					Kind: Added, NewPath: y.path, New: summary(y), NewNode: y.n,
+				})
+			}
+			continue
+		}
+		ch := Change{Path: x.path, NewPath: y.path, OldNode: x.n, NewNode: y.n}
+
+		if y.parent != nil && (x.parent == nil || x.parent.match != y.parent) || moved[y] {
+			ch.Kind = Moved
+			cc = append(cc, ch)
+		}
+
+		switch y.n.Type {
+		case html.TextNode:
+			if x.text != y.text {
+				ch.Kind, ch.Old, ch.New = TextChanged, x.text, y.text
+				cc = append(cc, ch)
+			}
+		case html.ElementNode:
+			for _, ac := range attrChanges(x.n.Attr, y.n.Attr) {
+				ch.Kind, ch.Attr, ch.Old, ch.New = AttrChanged, ac.Key, ac.old, ac.Val
+				cc = append(cc, ch)
+			}
+		}
+	}
+	return cc
+}
+
+// reordered reports nodes which stayed with their parent,
+// but changed the position among the siblings which stayed too.
+func reordered(b *tree) map[*node]bool {
+	moved := map[*node]bool{}
+	for _, y := range b.nodes {
+		if y.match == nil {
+			continue
+		}
+		var stay []*node
+		var pos []int
+		for _, k := range y.kids {
+			if k.match != nil && k.match.parent == y.match {
+				stay = append(stay, k)
+				pos = append(pos, k.match.pos)
+			}
+		}
+		for i, in := range increasing(pos) {
+			if !in {
+				moved[stay[i]] = true
+			}
+		}
+	}
+	return moved
+}
+
+// increasing marks a longest increasing subsequence of a.
+func increasing(a []int) []bool {
+	n := len(a)
+	length, prev := make([]int, n), make([]int, n)
+	best := -1
+	for i := range a {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if a[j] < a[i] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	in := make([]bool, n)
+	for i := best; i >= 0; i = prev[i] {
+		in[i] = true
+	}
+	return in
+}
+
+type attrChange struct {
+	html.Attribute
+	old string
+}
+
+func attrChanges(old, new []html.Attribute) (res []attrChange) {
+	val := func(aa []html.Attribute, k string) (string, bool) {
+		for _, a := range aa {
+			if a.Key == k {
+				return a.Val, true
+			}
+		}
+		return "", false
+	}
+	for _, a := range old {
+		if v, ok := val(new, a.Key); !ok || v != a.Val {
+			res = append(res, attrChange{html.Attribute{Key: a.Key, Val: v}, a.Val})
+		}
+	}
+	for _, a := range new {
+		if _, ok := val(old, a.Key); !ok {
+			res = append(res, attrChange{a, ""})
+		}
+	}
+	return res
+}
+
+func summary(x *node) string {
+	switch x.n.Type {
+	case html.TextNode:
+		s := x.text
+		if r := []rune(s); len(r) > 40 {
+			s = string(r[:40]) + "…"
+		}
+		return strconv.Quote(s)
+	case html.ElementNode:
+		return startTag(x.n)
+	}
+	return ""
+}
+
+func startTag(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		fmt.Fprintf(&b, " %s=%q", a.Key, a.Val)
+	}
+	b.WriteString(">")
+	return b.String()
+}
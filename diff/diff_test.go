@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/pred"
+)
+
+func TestCompare(t *testing.T) {
+	tab := []struct {
+		old, new string
+		exp      []string
+	}{
+		{`<p>same</p>`, "<p>same</p>\n", nil},
+		{`<h1>Old title</h1>`, `<h1>New title</h1>`,
+			[]string{`text    /html/body/h1/text() "Old title" -> "New title"`}},
+		{`<a href="/a" class="x">l</a>`, `<a href="/b" rel="me">l</a>`,
+			[]string{
+				`attr    /html/body/a class "x" -> ""`,
+				`attr    /html/body/a href "/a" -> "/b"`,
+				`attr    /html/body/a rel "" -> "me"`,
+			}},
+		{`<p>a</p><p class="note">b</p><p>c</p>`, `<p>a</p><p>c</p>`,
+			[]string{`removed /html/body/p[2] <p class="note">`}},
+		{`<ul><li>one</li><li>two</li></ul>`,
+			`<ul><li>one</li><li>two</li><li>three</li></ul>`,
+			[]string{`added   /html/body/ul/li[3] <li>`}},
+		{`<ul><li>one</li><li>two</li><li>three</li></ul>`,
+			`<ul><li>three</li><li>one</li><li>two</li></ul>`,
+			[]string{`moved   /html/body/ul/li[3] -> /html/body/ul/li[1]`}},
+		{`<div id="a"><p>long paragraph text</p></div><div id="b"></div>`,
+			`<div id="a"></div><div id="b"><p>long paragraph text</p></div>`,
+			[]string{`moved   /html/body/div[1]/p -> /html/body/div[2]/p`}},
+		{`<div id="main"><p>x</p></div>`, `<div id="main" class="wide"><p>y</p></div>`,
+			[]string{
+				`attr    /html/body/div class "" -> "wide"`,
+				`text    /html/body/div/p/text() "x" -> "y"`,
+			}},
+		{`<table><tr><td>1</td><td>2</td></tr><tr><td>3</td><td>4</td></tr></table>`,
+			`<table><tr><td>1</td><td>2</td></tr><tr><td>3</td><td>5</td></tr></table>`,
+			[]string{`text    /html/body/table/tbody/tr[2]/td[2]/text() "4" -> "5"`}},
+		{"<p>some   spaced\ntext</p>", "<p>some spaced text</p>", nil},
+		{`<p>keep</p><!-- c1 -->`, `<p>keep</p><!-- c2 -->`, nil},
+	}
+
+	for i, tc := range tab {
+		a, _ := htmlx.FinderFromString(tc.old)
+		b, _ := htmlx.FinderFromString(tc.new)
+
+		var s strings.Builder
+		if err := Compare(a, b).WriteText(&s); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		exp := strings.Join(tc.exp, "\n")
+		if exp != "" {
+			exp += "\n"
+		}
+		if res := s.String(); res != exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, exp)
+		}
+	}
+}
+
+func TestCompareSubtrees(t *testing.T) {
+	a, _ := htmlx.FinderFromString(`<div class="price">10</div>`)
+	b, _ := htmlx.FinderFromString(`<section><span class="price">12</span></section>`)
+
+	d := Compare(a.Find(pred.Class("price")), b.Find(pred.Class("price")))
+	if len(d.Changes) != 1 || d.Changes[0].Kind != TextChanged ||
+		d.Changes[0].NewPath != "/text()" {
+		t.Errorf("mismatch: %+v", d.Changes)
+	}
+
+	d = Compare(htmlx.Finder{}, b)
+	if len(d.Changes) != 1 || d.Changes[0].Kind != Added {
+		t.Errorf("mismatch for empty: %+v", d.Changes)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	a, _ := htmlx.FinderFromString(`<p title="t">x</p>`)
+	b, _ := htmlx.FinderFromString(`<p title="u">x</p><hr>`)
+
+	var s strings.Builder
+	Compare(a, b).WriteJSON(&s)
+
+	var res []map[string]string
+	if err := json.Unmarshal([]byte(s.String()), &res); err != nil {
+		t.Fatal(err)
+	}
+	exp := []map[string]string{
+		{"kind": "attr", "path": "/html/body/p", "newPath": "/html/body/p",
+			"attr": "title", "old": "t", "new": "u"},
+		{"kind": "added", "newPath": "/html/body/hr", "new": "<hr>"},
+	}
+	if len(res) != len(exp) {
+		t.Fatalf("mismatch:\ngot %v\nexp %v", res, exp)
+	}
+	for i := range exp {
+		for k, v := range exp[i] {
+			if res[i][k] != v {
+				t.Errorf("change[%d].%s: got %q, exp %q", i, k, res[i][k], v)
+			}
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	a, _ := htmlx.FinderFromString(
+		`<title>t</title><p>a</p><p>gone</p><h1>Old</h1><a href="/a">l</a>`)
+	b, _ := htmlx.FinderFromString(
+		`<title>t</title><p>a</p><h1>New</h1><a href="/b">l</a><hr>`)
+
+	var s strings.Builder
+	if err := Compare(a, b).WriteHTML(&s); err != nil {
+		t.Fatal(err)
+	}
+	exp := `<html><head><title>t</title><style>` + style + `</style></head>` +
+		`<body><p>a</p><p data-diff="removed">gone</p>` +
+		`<h1><del data-diff="removed">Old</del><ins data-diff="added">New</ins></h1>` +
+		`<a href="/b" data-diff="changed" data-diff-attrs="href">l</a>` +
+		`<hr data-diff="added"/></body></html>`
+	if res := s.String(); res != exp {
+		t.Errorf("mismatch:\ngot:\n%s\nexp:\n%s", res, exp)
+	}
+
+	// The input trees stay intact.
+	if res := b.String(); strings.Contains(res, "data-diff") {
+		t.Errorf("new tree modified: %s", res)
+	}
+}
+
+func TestWriteHTMLRoot(t *testing.T) {
+	node := func(n *html.Node) htmlx.Finder { return htmlx.FinderFromNode(n) }
+	tab := []struct {
+		a, b htmlx.Finder
+		exp  string
+	}{
+		{node(&html.Node{Type: html.TextNode, Data: "a"}),
+			node(&html.Node{Type: html.TextNode, Data: "b"}),
+			`<del data-diff="removed">a</del><ins data-diff="added">b</ins>`},
+		{node(&html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P}),
+			node(&html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P,
+				Attr: []html.Attribute{{Key: "id", Val: "x"}}}),
+			`<p id="x" data-diff="changed" data-diff-attrs="id"></p>`},
+	}
+	for i, tc := range tab {
+		var s strings.Builder
+		if err := Compare(tc.a, tc.b).WriteHTML(&s); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		exp := `<style>` + style + `</style>` + tc.exp
+		if res := s.String(); res != exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, exp)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package diff
+
+import (
+	"slices"
+
+	"golang.org/x/net/html"
+)
+
+// minDice is the share of common matched descendants needed
+// to pair two containers.
+const minDice = 0.5
+
+func match(a, b *tree) {
+	if a.root == nil || b.root == nil {
+		return
+	}
+	link(a.root, b.root)
+	matchIdentical(a, b)
+	matchIDs(a, b)
+	matchContainers(a, b)
+	for _, x := range a.nodes {
+		if x.match != nil {
+			matchKids(x, x.match)
+		}
+	}
+}
+
+func link(x, y *node) {
+	x.match, y.match = y, x
+}
+
+// matchIdentical pairs identical subtrees, largest first. Leaves are
+// paired only when unique in both trees, as a repeated short text says
+// little about where it came from.
+func matchIdentical(a, b *tree) {
+	inA, inB := map[uint64][]*node{}, map[uint64]int{}
+	for _, x := range a.nodes {
+		inA[x.hash] = append(inA[x.hash], x)
+	}
+	for _, y := range b.nodes {
+		inB[y.hash]++
+	}
+
+	ys := slices.Clone(b.nodes)
+	slices.SortStableFunc(ys, func(p, q *node) int { return q.size - p.size })
+
+	for _, y := range ys {
+		if y.match != nil {
+			continue
+		}
+		cands := inA[y.hash]
+		if y.size == 1 && (len(cands) != 1 || inB[y.hash] != 1) {
+			continue
+		}
+		var best *node
+		for _, x := range cands {
+			if x.match != nil {
+				continue
+			}
+			if best == nil {
+				best = x
+			}
+			if x.path == y.path {
+				best = x
+				break
+			}
+		}
+		if best != nil {
+			linkSubtrees(best, y)
+		}
+	}
+}
+
+func linkSubtrees(x, y *node) {
+	if x.match != nil || y.match != nil {
+		return
+	}
+	link(x, y)
+	if len(x.kids) != len(y.kids) {
+		return
+	}
+	for i := range x.kids {
+		linkSubtrees(x.kids[i], y.kids[i])
+	}
+}
+
+// matchIDs pairs elements of the same name and id.
+func matchIDs(a, b *tree) {
+	ids := map[string]*node{}
+	dup := map[string]bool{}
+	for _, x := range a.nodes {
+		if id := x.id(); id != "" && x.match == nil {
+			k := x.label + "#" + id
+			dup[k] = ids[k] != nil
+			ids[k] = x
+		}
+	}
+	for _, y := range b.nodes {
+		if id := y.id(); id != "" && y.match == nil {
+			k := y.label + "#" + id
+			if x := ids[k]; x != nil && !dup[k] && x.match == nil {
+				link(x, y)
+			}
+		}
+	}
+}
+
+// matchContainers pairs elements, bottom-up, with the ones of the same
+// name holding most of the matches of their descendants.
+func matchContainers(a, b *tree) {
+	for i := len(a.nodes) - 1; i >= 0; i-- {
+		x := a.nodes[i]
+		if x.match != nil || x.n.Type != html.ElementNode || x.size == 1 {
+			continue
+		}
+
+		var best *node
+		bestDice := 0.0
+		seen := map[*node]bool{}
+		for _, d := range a.nodes[x.idx+1 : x.idx+x.size] {
+			if d.match == nil {
+				continue
+			}
+			for y := d.match.parent; y != nil && !seen[y]; y = y.parent {
+				seen[y] = true
+				if y.match != nil || y.label != x.label {
+					continue
+				}
+				if dice := dice(x, y, a); dice > bestDice {
+					best, bestDice = y, dice
+				}
+			}
+		}
+		if best != nil && bestDice >= minDice {
+			link(x, best)
+		}
+	}
+}
+
+func dice(x, y *node, a *tree) float64 {
+	common := 0
+	for _, d := range a.nodes[x.idx+1 : x.idx+x.size] {
+		if d.match != nil && y.descendant(d.match) {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(x.size-1+y.size-1)
+}
+
+// matchKids pairs the unmatched children of matched parents,
+// in order: first by name and class, then by name only.
+func matchKids(x, y *node) {
+	for _, key := range []func(*node) string{(*node).key, func(n *node) string { return n.label }} {
+		var xs, ys []*node
+		for _, k := range x.kids {
+			if k.match == nil {
+				xs = append(xs, k)
+			}
+		}
+		for _, k := range y.kids {
+			if k.match == nil {
+				ys = append(ys, k)
+			}
+		}
+		for _, p := range lcs(xs, ys, key) {
+			link(p[0], p[1])
+		}
+	}
+}
+
+// lcs returns the pairs of a longest common subsequence of xs and ys,
+// comparing the keys.
+func lcs(xs, ys []*node, key func(*node) string) [][2]*node {
+	n, m := len(xs), len(ys)
+	if n == 0 || m == 0 {
+		return nil
+	}
+	t := make([][]int, n+1)
+	for i := range t {
+		t[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case key(xs[i]) == key(ys[j]):
+				t[i][j] = t[i+1][j+1] + 1
+			case t[i+1][j] >= t[i][j+1]:
+				t[i][j] = t[i+1][j]
+			default:
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+	var res [][2]*node
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case key(xs[i]) == key(ys[j]):
+			res = append(res, [2]*node{xs[i], ys[j]})
+			i++
+			j++
+		case t[i+1][j] >= t[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return res
+}
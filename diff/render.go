@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WriteText writes the changes one per line, like:
+//
+//	removed /html/body/p[2] <p class="note">
+//	moved   /html/body/ul/li[3] -> /html/body/ul/li[1]
+//	attr    /html/body/a href "/a" -> "/b"
+func (d *Diff) WriteText(w io.Writer) error {
+	for _, c := range d.Changes {
+		var s string
+		switch c.Kind {
+		case Added:
+			s = c.NewPath + " " + c.New
+		case Removed:
+			s = c.Path + " " + c.Old
+		case Moved:
+			s = c.Path + " -> " + c.NewPath
+		case TextChanged:
+			s = c.NewPath + " " + strconv.Quote(c.Old) + " -> " + strconv.Quote(c.New)
+		case AttrChanged:
+			s = c.NewPath + " " + c.Attr + " " + strconv.Quote(c.Old) + " -> " + strconv.Quote(c.New)
+		}
+		if _, err := fmt.Fprintf(w, "%-7s %s\n", c.Kind, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the changes as a JSON array.
+func (d *Diff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.Changes)
+}
+
+const style = `[data-diff=added]{background:#e6ffec;outline:1px solid #2da44e}
+[data-diff=removed]{background:#ffebe9;outline:1px solid #cf222e;text-decoration:line-through}
+[data-diff=moved]{outline:1px dashed #9a6700}
+[data-diff=changed]{outline:1px solid #0969da}
+`
+
+// WriteHTML writes the new tree annotated with the changes, marked
+// with data-diff attributes and highlighted by an added stylesheet.
+// Removed nodes are put back where they were, changed text is shown
+// as a <del> and <ins> pair, and the names of changed attributes
+// are listed in data-diff-attrs.
+func (d *Diff) WriteHTML(w io.Writer) error {
+	if d.new.root == nil {
+		return nil
+	}
+	copies := map[*html.Node]*html.Node{}
+	// The root is held by a document, for a changed root to be marked
+	// in place like any other node.
+	root := &html.Node{Type: html.DocumentNode}
+	root.AppendChild(clone(d.new.root.n, copies))
+
+	// Removed nodes go first, as they are positioned relative to
+	// the copies of their siblings, which the other marks may replace.
+	for _, c := range d.Changes {
+		if c.Kind != Removed {
+			continue
+		}
+		x := d.old.byNode[c.OldNode]
+		if x.parent == nil {
+			continue
+		}
+		parent := copies[x.parent.match.n]
+		var after *html.Node
+		for i := x.pos - 1; i >= 0 && after == nil; i-- {
+			if s := x.parent.kids[i].match; s != nil && s.parent == x.parent.match {
+				after = copies[s.n]
+			}
+		}
+		m := mark(clone(x.n, nil), "removed")
+		if after != nil {
+			parent.InsertBefore(m, after.NextSibling)
+		} else {
+			parent.InsertBefore(m, parent.FirstChild)
+		}
+	}
+
+	for _, c := range d.Changes {
+		var n *html.Node
+		if c.NewNode != nil {
+			n = copies[c.NewNode]
+		}
+		switch c.Kind {
+		case Added:
+			mark(n, "added")
+		case Moved:
+			mark(n, "moved")
+		case TextChanged:
+			old := mark(&html.Node{Type: html.TextNode, Data: c.Old}, "removed")
+			n.Parent.InsertBefore(old, n)
+			mark(n, "added")
+		case AttrChanged:
+			if n.Type != html.ElementNode {
+				continue
+			}
+			if _, ok := attrVal(n, "data-diff"); !ok {
+				setAttr(n, "data-diff", "changed")
+			}
+			v, _ := attrVal(n, "data-diff-attrs")
+			setAttr(n, "data-diff-attrs", strings.TrimSpace(v+" "+c.Attr))
+		}
+	}
+
+	st := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+	st.AppendChild(&html.Node{Type: html.TextNode, Data: style})
+	if head := findHead(root); head != nil {
+		head.AppendChild(st)
+	} else if err := html.Render(w, st); err != nil {
+		return err
+	}
+	return html.Render(w, root)
+}
+
+// mark sets data-diff on an element, or wraps a text node,
+// in place, in an element having it.
+func mark(n *html.Node, kind string) *html.Node {
+	if n.Type == html.ElementNode {
+		setAttr(n, "data-diff", kind)
+		return n
+	}
+	tag, a := "span", atom.Span
+	switch kind {
+	case "added":
+		tag, a = "ins", atom.Ins
+	case "removed":
+		tag, a = "del", atom.Del
+	}
+	el := &html.Node{
+		Type: html.ElementNode, Data: tag, DataAtom: a,
+		Attr: []html.Attribute{{Key: "data-diff", Val: kind}},
+	}
+	if n.Parent != nil {
+		n.Parent.InsertBefore(el, n)
+		n.Parent.RemoveChild(n)
+	}
+	el.AppendChild(n)
+	return el
+}
+
+func clone(n *html.Node, copies map[*html.Node]*html.Node) *html.Node {
+	c := &html.Node{
+		Type: n.Type, DataAtom: n.DataAtom, Data: n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	if copies != nil {
+		copies[n] = c
+	}
+	for k := n.FirstChild; k != nil; k = k.NextSibling {
+		c.AppendChild(clone(k, copies))
+	}
+	return c
+}
+
+func findHead(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Head {
+		return n
+	}
+	if n.Type != html.DocumentNode && n.DataAtom != atom.Html {
+		return nil
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if h := findHead(c); h != nil {
+			return h
+		}
+	}
+	return nil
+}
+
+func attrVal(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// node wraps a compared html.Node.
+type node struct {
+	n      *html.Node
+	parent *node
+	kids   []*node
+	pos    int // index among the parent's kids
+	idx    int // index in the pre-order of the tree
+	size   int // nodes in the subtree, including this one
+	path   string
+	label  string // element name or text()
+	text   string // collapsed text of text nodes
+	hash   uint64 // of the subtree content
+	match  *node
+}
+
+type tree struct {
+	root   *node
+	nodes  []*node // in pre-order
+	byNode map[*html.Node]*node
+}
+
+func newTree(h *html.Node) *tree {
+	t := &tree{byNode: map[*html.Node]*node{}}
+	if h != nil {
+		t.root = t.add(h, nil, "")
+	}
+	return t
+}
+
+func (t *tree) add(h *html.Node, parent *node, path string) *node {
+	x := &node{n: h, parent: parent, idx: len(t.nodes), size: 1, path: path}
+	x.label = label(h)
+	if h.Type == html.TextNode {
+		x.text = strings.Join(strings.Fields(h.Data), " ")
+	}
+	t.nodes = append(t.nodes, x)
+	t.byNode[h] = x
+
+	var kids []*html.Node
+	count := map[string]int{}
+	for c := h.FirstChild; c != nil; c = c.NextSibling {
+		if significant(c) {
+			kids = append(kids, c)
+			count[label(c)]++
+		}
+	}
+	seen := map[string]int{}
+	for i, c := range kids {
+		l := label(c)
+		step := l
+		if count[l] > 1 {
+			seen[l]++
+			step = fmt.Sprintf("%s[%d]", l, seen[l])
+		}
+		k := t.add(c, x, path+"/"+step)
+		k.pos = i
+		x.kids = append(x.kids, k)
+		x.size += k.size
+	}
+	x.hash = x.computeHash()
+	return x
+}
+
+func significant(n *html.Node) bool {
+	switch n.Type {
+	case html.ElementNode:
+		return true
+	case html.TextNode:
+		return strings.TrimSpace(n.Data) != ""
+	}
+	return false
+}
+
+func label(n *html.Node) string {
+	switch n.Type {
+	case html.ElementNode:
+		return n.Data
+	case html.TextNode:
+		return "text()"
+	}
+	return ""
+}
+
+func (x *node) computeHash() uint64 {
+	h := fnv.New64a()
+	switch x.n.Type {
+	case html.TextNode:
+		io.WriteString(h, "#"+x.text)
+	case html.ElementNode:
+		io.WriteString(h, "<"+x.n.Data)
+		aa := slices.Clone(x.n.Attr)
+		slices.SortFunc(aa, func(a, b html.Attribute) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+		for _, a := range aa {
+			io.WriteString(h, " "+a.Key+"="+a.Val)
+		}
+	}
+	var buf [8]byte
+	for _, k := range x.kids {
+		binary.LittleEndian.PutUint64(buf[:], k.hash)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// descendant reports whether y is in the subtree of x, excluding x.
+func (x *node) descendant(y *node) bool {
+	return y.idx > x.idx && y.idx < x.idx+x.size
+}
+
+func (x *node) key() string {
+	if x.n.Type != html.ElementNode {
+		return x.label
+	}
+	for _, a := range x.n.Attr {
+		if a.Key == "class" {
+			return x.label + "." + strings.Join(strings.Fields(a.Val), ".")
+		}
+	}
+	return x.label
+}
+
+func (x *node) id() string {
+	if x.n.Type != html.ElementNode {
+		return ""
+	}
+	for _, a := range x.n.Attr {
+		if a.Key == "id" {
+			return a.Val
+		}
+	}
+	return ""
+}
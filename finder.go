@@ -7,6 +7,7 @@ import (
 	"golang.org/x/net/html"
 
 	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/pos"
 	"github.com/wkhere/htmlx/pred"
 )
 
@@ -29,6 +30,17 @@ func FinderFromString(s string) (Finder, error) {
 	return FinderFromData(strings.NewReader(s))
 }
 
+// FinderFromDataWithPos parses like FinderFromData, returning also
+// the source positions of the nodes.
+func FinderFromDataWithPos(r io.Reader) (Finder, pos.Map, error) {
+	h, m, err := pos.Parse(r)
+	return Finder{h}, m, err
+}
+
+func FinderFromStringWithPos(s string) (Finder, pos.Map, error) {
+	return FinderFromDataWithPos(strings.NewReader(s))
+}
+
 func (f Finder) IsEmpty() bool {
 	return f.Node == nil
 }
@@ -121,6 +121,33 @@ func TestFromString(t *testing.T) {
 	}
 }
 
+func TestFromStringWithPos(t *testing.T) {
+	top, positions, _ := FinderFromStringWithPos("<p>a</p>\n<div id=\"1\"></div>")
+
+	div := top.Find(p.ID("1"))
+	if res := positions.Of(div.Node).Start.Start.String(); res != "2:1" {
+		t.Errorf("div position: got %s, exp 2:1", res)
+	}
+	other, _ := FinderFromString(`<div id="1"></div>`)
+	if positions.Of(other.Find(p.ID("1")).Node).IsValid() {
+		t.Error("position of a node of another tree")
+	}
+}
+
+func TestFindCSS(t *testing.T) {
+	top, _ := FinderFromString(
+		`<ul id="1"><li class="a">x</li><li class="b">y</li></ul><li class="a">z</li>`)
+
+	res := top.FindAll(p.MustCSS("ul > li.a, li.b")).Collect()
+	if len(res) != 2 || res[0].InnerText() != "x" || res[1].InnerText() != "y" {
+		t.Errorf("mismatch: %v", res)
+	}
+
+	if _, err := p.CSS("ul >"); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestFind(t *testing.T) {
 	f := testdata("simple.html")
 	top, _ := FinderFromData(f)
@@ -0,0 +1,256 @@
+// Package format pretty-prints HTML: block elements go on their own
+// lines, indented by depth, while inline content is wrapped to the line
+// width, breaking only where whitespace already was.
+// Whitespace-sensitive elements, like <pre>, are written as they are.
+//
+// The output re-parses to a tree equivalent to the input one,
+// up to insignificant whitespace.
+package format
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+const (
+	DefaultIndent = "  "
+	DefaultWidth  = 80
+)
+
+type Formatter struct {
+	// Indent is used once per nesting level; DefaultIndent if empty.
+	Indent string
+
+	// Width is the maximum line width; DefaultWidth if zero.
+	// Long words, tags and preserved content may exceed it.
+	Width int
+
+	// WrapAttrs puts each attribute on its own line when a start tag
+	// of a block element does not fit the width.
+	WrapAttrs bool
+}
+
+// Source formats the HTML document in src.
+func (f Formatter) Source(src []byte) ([]byte, error) {
+	doc, err := htmlx.FinderFromData(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = f.Format(&b, doc)
+	return b.Bytes(), err
+}
+
+// String formats the subtree using default options.
+func String(fn htmlx.Finder) string {
+	var b strings.Builder
+	Formatter{}.Format(&b, fn)
+	return b.String()
+}
+
+// Format writes the subtree rooted at fn.
+func (f Formatter) Format(w io.Writer, fn htmlx.Finder) error {
+	if fn.IsEmpty() {
+		return nil
+	}
+	if f.Indent == "" {
+		f.Indent = DefaultIndent
+	}
+	if f.Width <= 0 {
+		f.Width = DefaultWidth
+	}
+	p := &printer{Formatter: f, w: w}
+
+	switch n := fn.Node; {
+	case n.Type == html.DocumentNode:
+		p.children(n, 0)
+	case blockish(n):
+		p.element(n, 0)
+	default:
+		p.run([]*html.Node{n}, 0)
+	}
+	return p.err
+}
+
+type printer struct {
+	Formatter
+	w   io.Writer
+	err error
+}
+
+func (p *printer) line(depth int, s string) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = io.WriteString(p.w, strings.Repeat(p.Indent, depth)+s+"\n")
+}
+
+// children writes block content, grouping runs of inline nodes.
+func (p *printer) children(n *html.Node, depth int) {
+	var run []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.DoctypeNode:
+			p.run(run, depth)
+			run = nil
+			var b strings.Builder
+			html.Render(&b, c)
+			p.line(depth, b.String())
+		case blockish(c):
+			p.run(run, depth)
+			run = nil
+			p.element(c, depth)
+		default:
+			run = append(run, c)
+		}
+	}
+	p.run(run, depth)
+}
+
+func (p *printer) element(n *html.Node, depth int) {
+	if elem.Preserved(n) {
+		var b strings.Builder
+		html.Render(&b, n)
+		p.line(depth, b.String())
+		return
+	}
+
+	start, end := p.startTag(n), "</"+n.Data+">"
+	if elem.Void(n) {
+		p.startLines(n, start, depth)
+		return
+	}
+
+	if hasBlockChild(n) {
+		p.startLines(n, start, depth)
+		p.children(n, depth+1)
+		p.line(depth, end)
+		return
+	}
+
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	tt := p.tokens(nodes)
+	if len(tt) == 0 {
+		p.line(depth, start+end)
+		return
+	}
+	if s, ok := oneLine(tt); ok &&
+		width(p.Indent)*depth+width(start)+width(s)+width(end) <= p.Width {
+		p.line(depth, start+s+end)
+		return
+	}
+	p.startLines(n, start, depth)
+	p.wrap(tt, depth+1)
+	p.line(depth, end)
+}
+
+// startLines writes the start tag, wrapping the attributes if asked for.
+func (p *printer) startLines(n *html.Node, start string, depth int) {
+	if !p.WrapAttrs || len(n.Attr) < 2 ||
+		width(p.Indent)*depth+width(start) <= p.Width {
+		p.line(depth, start)
+		return
+	}
+	p.line(depth, "<"+n.Data)
+	for i, a := range n.Attr {
+		s := attrString(a)
+		if i == len(n.Attr)-1 {
+			s += ">"
+		}
+		p.line(depth+1, s)
+	}
+}
+
+// run writes a run of inline nodes, wrapped.
+func (p *printer) run(nodes []*html.Node, depth int) {
+	if len(nodes) == 0 {
+		return
+	}
+	p.wrap(p.tokens(nodes), depth)
+}
+
+func (p *printer) wrap(tt []token, depth int) {
+	avail := p.Width - width(p.Indent)*depth
+	var line strings.Builder
+	n := 0
+	flush := func() {
+		if n > 0 {
+			p.line(depth, line.String())
+		}
+		line.Reset()
+		n = 0
+	}
+	for _, t := range tt {
+		tw := width(t.s)
+		if n > 0 && t.space && n+1+tw > avail {
+			flush()
+		}
+		if n > 0 && t.space {
+			line.WriteByte(' ')
+			n++
+		}
+		line.WriteString(t.s)
+		n += tw
+		if t.brk {
+			flush()
+		}
+	}
+	flush()
+}
+
+func (p *printer) startTag(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		b.WriteString(" " + attrString(a))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func attrString(a html.Attribute) string {
+	k := a.Key
+	if a.Namespace != "" {
+		k = a.Namespace + ":" + k
+	}
+	if a.Val == "" {
+		return k
+	}
+	return k + `="` + strings.NewReplacer("&", "&amp;", `"`, "&quot;").Replace(a.Val) + `"`
+}
+
+// blockish reports elements laid out on their own lines: block ones,
+// and inline ones containing blocks.
+func blockish(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	return elem.Block(n) || hasBlockChild(n)
+}
+
+func hasBlockChild(n *html.Node) bool {
+	if elem.Preserved(n) || n.DataAtom == atom.Template {
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if blockish(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func width(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,92 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/htmltest"
+)
+
+func TestFormat(t *testing.T) {
+	tab := []struct {
+		f        Formatter
+		html, pp string
+	}{
+		{Formatter{}, "<!DOCTYPE html><html><head><title>T</title></head><body><p>a <b>b</b>   c</p><div><p>x</p>y<br>z</div></body></html>",
+			`<!DOCTYPE html>
+<html>
+  <head>
+    <title>T</title>
+  </head>
+  <body>
+    <p>a <b>b</b> c</p>
+    <div>
+      <p>x</p>
+      y<br>
+      z
+    </div>
+  </body>
+</html>
+`},
+		{Formatter{Width: 20, Indent: "\t"}, "<p>one two three <i>four five</i>six seven</p>",
+			"<html>\n\t<head></head>\n\t<body>\n\t\t<p>\n\t\t\tone two three\n\t\t\t<i>four\n\t\t\tfive</i>six seven\n\t\t</p>\n\t</body>\n</html>\n"},
+		{Formatter{}, "<div><pre>\n\n  keep\n   this</pre><textarea> a  b</textarea></div>",
+			"<html>\n  <head></head>\n  <body>\n    <div>\n      <pre>\n\n  keep\n   this</pre>\n      <textarea> a  b</textarea>\n    </div>\n  </body>\n</html>\n"},
+		{Formatter{Width: 30, WrapAttrs: true}, `<div id="main" class="wide content" data-x="1 &amp; &quot;2&quot;"><hr></div>`,
+			"<html>\n  <head></head>\n  <body>\n    <div\n      id=\"main\"\n      class=\"wide content\"\n      data-x=\"1 &amp; &quot;2&quot;\">\n      <hr>\n    </div>\n  </body>\n</html>\n"},
+		{Formatter{}, `<ul><li>1 &lt; 2</li><li><!-- c --><script>if (a<b) {}</script></li></ul>`,
+			"<html>\n  <head></head>\n  <body>\n    <ul>\n      <li>1 &lt; 2</li>\n      <li><!-- c --><script>if (a<b) {}</script></li>\n    </ul>\n  </body>\n</html>\n"},
+	}
+
+	for i, tc := range tab {
+		res, err := tc.f.Source([]byte(tc.html))
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if string(res) != tc.pp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.pp)
+		}
+		if err := htmltest.EquivalentLayout(tc.html, string(res)); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+	}
+}
+
+func TestFormatSubtree(t *testing.T) {
+	top, _ := htmlx.FinderFromString(`<div><p>x</p></div><span>y</span>`)
+	body := top.FirstChild().LastChild()
+
+	if res, exp := String(body.FirstChild()), "<div>\n  <p>x</p>\n</div>\n"; res != exp {
+		t.Errorf("got %q, exp %q", res, exp)
+	}
+	if res, exp := String(body.LastChild()), "<span>y</span>\n"; res != exp {
+		t.Errorf("got %q, exp %q", res, exp)
+	}
+	if res := String(htmlx.Finder{}); res != "" {
+		t.Errorf("got %q, exp empty", res)
+	}
+}
+
+func TestEquivalentFiles(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("..", "testdata", "*.html"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := Formatter{Width: 60, WrapAttrs: true}
+		res, err := f.Source(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := htmltest.EquivalentLayout(string(data), string(res)); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+		again, _ := f.Source(res)
+		if string(again) != string(res) {
+			t.Errorf("%s: formatting again changed the output", file)
+		}
+	}
+}
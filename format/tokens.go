@@ -0,0 +1,128 @@
+package format
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// token is an unbreakable piece of inline content.
+type token struct {
+	s     string
+	space bool // preceded by whitespace, so a line may break before it
+	brk   bool // a line break follows, as after <br>
+}
+
+type tokenizer struct {
+	tt    []token
+	cur   strings.Builder
+	space bool
+}
+
+func (t *tokenizer) emit(s string) {
+	if t.space && t.cur.Len() > 0 {
+		t.flush(false)
+	}
+	if t.cur.Len() == 0 {
+		t.tt = append(t.tt, token{space: t.space && len(t.tt) > 0})
+	}
+	t.space = false
+	t.cur.WriteString(s)
+}
+
+func (t *tokenizer) flush(brk bool) {
+	if t.cur.Len() == 0 {
+		return
+	}
+	last := &t.tt[len(t.tt)-1]
+	last.s = t.cur.String()
+	last.brk = brk
+	t.cur.Reset()
+}
+
+// tokens splits inline nodes into tokens, collapsing whitespace.
+// Leading and trailing whitespace is dropped, as the nodes are
+// surrounded by block boundaries.
+func (p *printer) tokens(nodes []*html.Node) []token {
+	t := &tokenizer{}
+	for _, n := range nodes {
+		p.inline(t, n)
+	}
+	t.flush(false)
+	return t.tt
+}
+
+func (p *printer) inline(t *tokenizer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if elem.RawText(n.Parent) {
+			t.emit(n.Data)
+			return
+		}
+		if s := n.Data; s != "" && isSpace(rune(s[0])) {
+			t.space = true
+		}
+		for i, w := range strings.FieldsFunc(n.Data, isSpace) {
+			if i > 0 {
+				t.space = true
+			}
+			t.emit(escapeText(w))
+		}
+		if s := n.Data; s != "" && isSpace(rune(s[len(s)-1])) {
+			t.space = true
+		}
+
+	case html.CommentNode:
+		t.emit("<!--" + n.Data + "-->")
+
+	case html.ElementNode:
+		if elem.Preserved(n) {
+			var b strings.Builder
+			html.Render(&b, n)
+			t.emit(b.String())
+			return
+		}
+		t.emit(p.startTag(n))
+		if elem.Void(n) {
+			if n.DataAtom == atom.Br {
+				t.flush(true)
+				t.space = false
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			p.inline(t, c)
+		}
+		t.emit("</" + n.Data + ">")
+	}
+}
+
+// oneLine joins the tokens, unless a line break is forced among them.
+func oneLine(tt []token) (string, bool) {
+	var b strings.Builder
+	for i, t := range tt {
+		if t.brk && i < len(tt)-1 {
+			return "", false
+		}
+		if t.space {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.s)
+	}
+	return b.String(), true
+}
+
+func isSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
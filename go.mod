@@ -3,8 +3,16 @@ module github.com/wkhere/htmlx
 go 1.25.0
 
 require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/andybalholm/cascadia v1.3.3
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/pflag v1.0.6
 	golang.org/x/net v0.55.0
+	golang.org/x/term v0.45.0
+	golang.org/x/text v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
+require golang.org/x/sys v0.47.0 // indirect
+
 retract v0.15.7
@@ -0,0 +1,475 @@
+// Package input lists HTML inputs from files, directories, glob
+// patterns, gzip or zstd compressed files, and zip or tar archives,
+// whose entries become separate inputs, as do the HTML documents
+// of WARC, HAR and MHTML web archives.
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/wkhere/htmlx"
+)
+
+// Input is a single HTML document to read.
+type Input struct {
+	// Name identifies the input, like dir/page.html,
+	// or site.zip:page.html for an archive entry.
+	Name string
+
+	// Path is the local file holding the input as it is,
+	// empty if it is compressed or comes from an archive.
+	Path string
+
+	// URL is the original address of a document from a WARC, HAR
+	// or MHTML archive, which also is the part of its Name
+	// after the archive file name.
+	URL string
+
+	// ContentType, if known, is the Content-Type of the document,
+	// which may tell its charset.
+	ContentType string
+
+	// Open returns the input, decompressed.
+	Open func() (io.ReadCloser, error)
+}
+
+// Finder parses the input, transcoding it to UTF-8.
+func (in Input) Finder() (htmlx.Finder, error) {
+	r, err := in.Open()
+	if err != nil {
+		return htmlx.Finder{}, err
+	}
+	defer r.Close()
+	return htmlx.FinderFromDataWithCharset(r, htmlx.Charset{ContentType: in.ContentType})
+}
+
+// Options filter the files taken from directories and archives.
+// Patterns without a slash match the base name, the others match
+// the whole path relative to the directory or archive root;
+// a ** element matches any number of directories.
+type Options struct {
+	// Include, if set, lists the patterns of the files to take,
+	// otherwise .html, .htm and .xhtml files are taken,
+	// also the compressed ones.
+	Include []string
+
+	// Exclude lists the patterns of the files to skip.
+	Exclude []string
+}
+
+// List returns the inputs named by arg: a file, a directory walked
+// recursively, an archive, or a glob pattern, like pages/**/*.html,
+// whose matching files are listed as if they were named one by one.
+// A file named explicitly is taken regardless of the Options.
+func (o Options) List(arg string) ([]Input, error) {
+	info, err := os.Stat(arg)
+	switch {
+	case err == nil && info.IsDir():
+		fsys := os.DirFS(arg)
+		return o.walk(fsys, func(p string) string {
+			return filepath.Join(arg, filepath.FromSlash(p))
+		}, func(p string) (io.ReadCloser, error) {
+			return fsys.Open(p)
+		}, true)
+	case err == nil:
+		return o.file(arg)
+	case errors.Is(err, fs.ErrNotExist) && hasMeta(arg):
+		return o.glob(arg)
+	}
+	return nil, err
+}
+
+func (o Options) file(name string) ([]Input, error) {
+	switch archiveType(name) {
+	case "zip":
+		return o.zip(name)
+	case "tar":
+		return o.tar(name)
+	case "warc":
+		return docs(name, openWARC)
+	case "har":
+		return docs(name, openHAR)
+	case "mhtml":
+		return docs(name, openMHTML)
+	}
+	in := Input{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			f, err := os.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			return decompress(name, f)
+		},
+	}
+	if compression(name) == "" {
+		in.Path = name
+	}
+	return []Input{in}, nil
+}
+
+func (o Options) glob(pattern string) (list []Input, err error) {
+	pattern = filepath.ToSlash(pattern)
+	base, rest := ".", pattern
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		if j := strings.LastIndexByte(pattern[:i], '/'); j >= 0 {
+			base, rest = pattern[:j], pattern[j+1:]
+			if base == "" {
+				base = "/"
+			}
+		}
+	}
+
+	depth := strings.Count(rest, "/") + 1
+	if strings.Contains(rest, "**") {
+		depth = -1
+	}
+
+	var matches []string
+	err = fs.WalkDir(os.DirFS(base), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != "." && depth > 0 && strings.Count(p, "/")+1 >= depth {
+			return fs.SkipDir
+		}
+		if d.Type().IsRegular() && Match(rest, p) {
+			matches = append(matches, path.Join(base, p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no match for %s", pattern)
+	}
+	for _, m := range matches {
+		l, err := o.file(filepath.FromSlash(m))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l...)
+	}
+	return list, nil
+}
+
+// FS returns the inputs found walking fsys, named with the prefix
+// joined to their paths.
+func (o Options) FS(fsys fs.FS, prefix string) ([]Input, error) {
+	return o.walk(fsys, func(p string) string {
+		return path.Join(prefix, p)
+	}, func(p string) (io.ReadCloser, error) {
+		return fsys.Open(p)
+	}, false)
+}
+
+// walk lists the files of fsys, named by the name function and read
+// by the open one; local tells if the names are the paths of the files
+// in the OS.
+func (o Options) walk(fsys fs.FS, name func(string) string,
+	open func(string) (io.ReadCloser, error), local bool) (list []Input, err error) {
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || !o.take(p) {
+			return err
+		}
+		in := Input{
+			Name: name(p),
+			Open: func() (io.ReadCloser, error) {
+				f, err := open(p)
+				if err != nil {
+					return nil, err
+				}
+				return decompress(p, f)
+			},
+		}
+		if local && compression(p) == "" {
+			in.Path = in.Name
+		}
+		list = append(list, in)
+		return nil
+	})
+	return list, err
+}
+
+// zip lists the entries of a zip archive, remembering where the data
+// of each starts to read it directly when it is opened.
+func (o Options) zip(name string) ([]Input, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	entries := make(map[string]zipEntry, len(zr.File))
+	for _, f := range zr.File {
+		off, err := f.DataOffset()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", name, f.Name, err)
+		}
+		entries[f.Name] = zipEntry{off, int64(f.CompressedSize64), f.Method}
+	}
+	return o.walk(zr, func(p string) string {
+		return name + ":" + p
+	}, func(p string) (io.ReadCloser, error) {
+		return entries[p].open(name)
+	}, false)
+}
+
+// zipEntry is where the data of a zip archive entry is,
+// and how it is compressed.
+type zipEntry struct {
+	off, size int64
+	method    uint16
+}
+
+func (e zipEntry) open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := io.NewSectionReader(f, e.off, e.size)
+	switch e.method {
+	case zip.Store:
+		return readCloser{r, f}, nil
+	case zip.Deflate:
+		d := flate.NewReader(r)
+		return readCloser{d, closers{d, f}}, nil
+	}
+	f.Close()
+	return nil, fmt.Errorf("%s: %w", name, zip.ErrAlgorithm)
+}
+
+// tar lists the files of a tar archive. The data of a file in an
+// uncompressed archive is read directly when it is opened; a compressed
+// archive is read again up to the file.
+func (o Options) tar(name string) (list []Input, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := decompress(name, f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(r)
+	for {
+		p, size, err := nextFile(tr)
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if !o.take(p) {
+			continue
+		}
+		open := func() (io.ReadCloser, error) { return tarEntry(name, p) }
+		if compression(name) == "" {
+			off, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			open = func() (io.ReadCloser, error) {
+				return fileSection(name, off, size, p)
+			}
+		}
+		list = append(list, Input{Name: name + ":" + p, Open: open})
+	}
+}
+
+// nextFile skips to the next regular file of the archive,
+// returning its path and size.
+func nextFile(tr *tar.Reader) (string, int64, error) {
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			return "", 0, err
+		}
+		if h.Typeflag == tar.TypeReg {
+			return path.Clean(strings.TrimPrefix(h.Name, "./")), h.Size, nil
+		}
+	}
+}
+
+// fileSection opens the size bytes of the file name at off,
+// which hold the file p.
+func fileSection(name string, off, size int64, p string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(p, readCloser{io.NewSectionReader(f, off, size), f})
+}
+
+// tarEntry opens the file p of the archive, reading it from the start.
+func tarEntry(name, p string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(name, f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(r)
+	for {
+		q, _, err := nextFile(tr)
+		if err == io.EOF {
+			err = fs.ErrNotExist
+		}
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%s: %s: %w", name, p, err)
+		}
+		if q == p {
+			return decompress(p, readCloser{tr, r})
+		}
+	}
+}
+
+func (o Options) take(p string) bool {
+	for _, pat := range o.Exclude {
+		if matchName(pat, p) {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return isHTML(p)
+	}
+	for _, pat := range o.Include {
+		if matchName(pat, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTML(p string) bool {
+	if c := compression(p); c != "" {
+		p = strings.TrimSuffix(p, path.Ext(p))
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".html", ".htm", ".xhtml":
+		return true
+	}
+	return false
+}
+
+func matchName(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		p = path.Base(p)
+	}
+	return Match(pattern, p)
+}
+
+// Match reports whether the slash-separated path p matches the pattern,
+// with the path.Match syntax and ** matching any number of elements.
+func Match(pattern, p string) bool {
+	return match(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func match(pp, ss []string) bool {
+	for len(pp) > 0 {
+		if pp[0] == "**" {
+			for i := 0; i <= len(ss); i++ {
+				if match(pp[1:], ss[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(ss) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pp[0], ss[0]); !ok {
+			return false
+		}
+		pp, ss = pp[1:], ss[1:]
+	}
+	return len(ss) == 0
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func compression(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz", ".tgz":
+		return "gzip"
+	case ".zst", ".tzst":
+		return "zstd"
+	}
+	return ""
+}
+
+func archiveType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar"), strings.HasSuffix(name, ".tgz"),
+		strings.HasSuffix(name, ".tzst"),
+		strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tar.zst"):
+		return "tar"
+	case strings.HasSuffix(name, ".warc"), strings.HasSuffix(name, ".warc.gz"):
+		return "warc"
+	case strings.HasSuffix(name, ".har"), strings.HasSuffix(name, ".har.gz"):
+		return "har"
+	case strings.HasSuffix(name, ".mhtml"), strings.HasSuffix(name, ".mht"):
+		return "mhtml"
+	}
+	return ""
+}
+
+// decompress wraps rc by the extension of the name.
+func decompress(name string, rc io.ReadCloser) (io.ReadCloser, error) {
+	switch compression(name) {
+	case "gzip":
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return readCloser{zr, rc}, nil
+	case "zstd":
+		zr, err := zstd.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		d := zr.IOReadCloser()
+		return readCloser{d, closers{d, rc}}, nil
+	}
+	return rc, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type closers []io.Closer
+
+func (cc closers) Close() (err error) {
+	for _, c := range cc {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
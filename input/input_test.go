@@ -0,0 +1,274 @@
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestMatch(t *testing.T) {
+	tab := []struct {
+		pattern, path string
+		exp           bool
+	}{
+		{"*.html", "a.html", true},
+		{"*.html", "d/a.html", false},
+		{"d/*.html", "d/a.html", true},
+		{"**/*.html", "a.html", true},
+		{"**/*.html", "d/e/a.html", true},
+		{"d/**", "d/e/a.html", true},
+		{"d/**/x/*.htm", "d/e/f/x/a.htm", true},
+		{"d/**/x/*.htm", "d/e/f/a.htm", false},
+		{"[ab].html", "c.html", false},
+	}
+	for i, tc := range tab {
+		if res := Match(tc.pattern, tc.path); res != tc.exp {
+			t.Errorf("tc[%d] mismatch: got %v, exp %v", i, res, tc.exp)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("site/index.html", []byte("index"))
+	write("site/notes.txt", []byte("notes"))
+	write("site/sub/a.htm", []byte("a"))
+	write("site/sub/skip.html", []byte("skip"))
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte("gzipped"))
+	zw.Close()
+	write("site/sub/b.html.gz", b.Bytes())
+
+	b.Reset()
+	sw, _ := zstd.NewWriter(&b)
+	sw.Write([]byte("zstd"))
+	sw.Close()
+	write("c.html.zst", b.Bytes())
+
+	b.Reset()
+	zipw := zip.NewWriter(&b)
+	for _, name := range []string{"z/one.html", "z/two.txt"} {
+		w, _ := zipw.Create(name)
+		io.WriteString(w, name)
+	}
+	zipw.Close()
+	write("pages.zip", b.Bytes())
+
+	b.Reset()
+	gw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{"./t/one.html", "t/two.xhtml", "t/x.css"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(name)),
+			Typeflag: tar.TypeReg})
+		io.WriteString(tw, name)
+	}
+	tw.Close()
+	gw.Close()
+	write("pages.tar.gz", b.Bytes())
+
+	j := func(s string) string { return filepath.Join(dir, filepath.FromSlash(s)) }
+
+	tab := []struct {
+		arg  string
+		opt  Options
+		exp  []string // name=content
+		path []string
+	}{
+		{j("site"), Options{Exclude: []string{"skip.*"}},
+			[]string{
+				j("site/index.html") + "=index",
+				j("site/sub/a.htm") + "=a",
+				j("site/sub/b.html.gz") + "=gzipped",
+			},
+			[]string{j("site/index.html"), j("site/sub/a.htm"), ""},
+		},
+		{j("site"), Options{Include: []string{"sub/*.txt", "*.txt"}},
+			[]string{j("site/notes.txt") + "=notes"},
+			[]string{j("site/notes.txt")},
+		},
+		{j("c.html.zst"), Options{},
+			[]string{j("c.html.zst") + "=zstd"},
+			[]string{""},
+		},
+		{j("site/notes.txt"), Options{},
+			[]string{j("site/notes.txt") + "=notes"},
+			[]string{j("site/notes.txt")},
+		},
+		{j("pages.zip"), Options{},
+			[]string{j("pages.zip") + ":z/one.html=z/one.html"},
+			[]string{""},
+		},
+		{j("pages.tar.gz"), Options{},
+			[]string{
+				j("pages.tar.gz") + ":t/one.html=./t/one.html",
+				j("pages.tar.gz") + ":t/two.xhtml=t/two.xhtml",
+			},
+			[]string{"", ""},
+		},
+		{dir + "/**/*.html", Options{},
+			[]string{
+				j("site/index.html") + "=index",
+				j("site/sub/skip.html") + "=skip",
+			},
+			[]string{j("site/index.html"), j("site/sub/skip.html")},
+		},
+		{dir + "/site/*/*.htm*", Options{},
+			[]string{
+				j("site/sub/a.htm") + "=a",
+				j("site/sub/b.html.gz") + "=gzipped",
+				j("site/sub/skip.html") + "=skip",
+			},
+			[]string{j("site/sub/a.htm"), "", j("site/sub/skip.html")},
+		},
+		{dir + "/*.zip", Options{},
+			[]string{j("pages.zip") + ":z/one.html=z/one.html"},
+			[]string{""},
+		},
+	}
+
+	for i, tc := range tab {
+		list, err := tc.opt.List(tc.arg)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		var res, paths []string
+		for _, in := range list {
+			r, err := in.Open()
+			if err != nil {
+				t.Errorf("tc[%d]: %s: %v", i, in.Name, err)
+				continue
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Errorf("tc[%d]: %s: %v", i, in.Name, err)
+			}
+			res = append(res, in.Name+"="+string(data))
+			paths = append(paths, in.Path)
+		}
+		if !slices.Equal(res, tc.exp) {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i,
+				strings.Join(res, "\n"), strings.Join(tc.exp, "\n"))
+		}
+		if !slices.Equal(paths, tc.path) {
+			t.Errorf("tc[%d] paths mismatch:\ngot:  %q\nexp:  %q", i, paths, tc.path)
+		}
+	}
+
+	for _, arg := range []string{j("nonexistent"), j("*.nothing")} {
+		if _, err := (Options{}).List(arg); err == nil {
+			t.Errorf("%s: expected error", arg)
+		}
+	}
+}
+
+func TestArchiveOpen(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := range 200 {
+		names = append(names, fmt.Sprintf("p%03d.html", i))
+	}
+
+	tarData := func() []byte {
+		var b bytes.Buffer
+		tw := tar.NewWriter(&b)
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644,
+				Size: int64(len(name)), Typeflag: tar.TypeReg})
+			io.WriteString(tw, name)
+		}
+		tw.Close()
+		return b.Bytes()
+	}
+	zipData := func() []byte {
+		var b bytes.Buffer
+		zw := zip.NewWriter(&b)
+		for i, name := range names {
+			method := zip.Deflate
+			if i%2 == 0 {
+				method = zip.Store
+			}
+			w, _ := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+			io.WriteString(w, name)
+		}
+		zw.Close()
+		return b.Bytes()
+	}
+	tgzData := func() []byte {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		zw.Write(tarData())
+		zw.Close()
+		return b.Bytes()
+	}
+
+	// A spoiled archive can't be read again from the start: its first
+	// headers are wiped, or the central directory of a zip, so only
+	// entries read directly where they were listed open.
+	tab := []struct {
+		name  string
+		data  func() []byte
+		spoil func([]byte)
+	}{
+		{"pages.tar", tarData, func(b []byte) { clear(b[:len(b)/2]) }},
+		{"pages.zip", zipData, func(b []byte) { clear(b[len(b)-22:]) }},
+		{"pages.tgz", tgzData, nil},
+	}
+
+	for i, tc := range tab {
+		p := filepath.Join(dir, tc.name)
+		data := tc.data()
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		list, err := (Options{}).List(p)
+		if err != nil {
+			t.Fatalf("tc[%d]: %v", i, err)
+		}
+		if tc.spoil != nil {
+			tc.spoil(data)
+			if err := os.WriteFile(p, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			list = list[len(list)/2:]
+		}
+		var res, exp []string
+		for _, in := range slices.Backward(list) {
+			r, err := in.Open()
+			if err != nil {
+				t.Errorf("tc[%d]: %s: %v", i, in.Name, err)
+				continue
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			res = append(res, string(data))
+			exp = append(exp, strings.TrimPrefix(in.Name, p+":"))
+		}
+		if !slices.Equal(res, exp) {
+			t.Errorf("tc[%d] mismatch:\ngot:  %q\nexp:  %q", i, res, exp)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// A docReader reads the HTML documents of a web archive one by one.
+type docReader interface {
+	// next returns the URL and the content type of the next document,
+	// and a reader of its body valid until the following call;
+	// io.EOF after the last one.
+	next() (url, contentType string, body io.Reader, err error)
+
+	// mark tells where the document last returned by next starts.
+	mark() mark
+
+	io.Closer
+}
+
+// A mark is where a document of a web archive starts: reading the
+// archive from the offset off, it is the one after skip others.
+// A document of a seekable archive is marked by its own offset,
+// while one of an archive compressed as a whole is counted from the start.
+type mark struct {
+	off  int64
+	skip int
+}
+
+// docs lists the documents of a web archive, marking each to read it
+// from there when it is opened.
+func docs(name string, open func(string, mark) (docReader, error)) (list []Input, err error) {
+	dr, err := open(name, mark{})
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+	for i := 0; ; i++ {
+		url, ct, _, err := dr.next()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		m := dr.mark()
+		list = append(list, Input{
+			Name:        name + ":" + url,
+			URL:         url,
+			ContentType: ct,
+			Open:        func() (io.ReadCloser, error) { return doc(name, open, i, m) },
+		})
+	}
+}
+
+// doc opens the i-th document of a web archive, marked by m.
+func doc(name string, open func(string, mark) (docReader, error), i int, m mark) (io.ReadCloser, error) {
+	dr, err := open(name, m)
+	if err != nil {
+		return nil, err
+	}
+	for j := 0; ; j++ {
+		_, _, body, err := dr.next()
+		if err == io.EOF {
+			err = fs.ErrNotExist
+		}
+		if err != nil {
+			dr.Close()
+			return nil, fmt.Errorf("%s: document %d: %w", name, i, err)
+		}
+		if j == m.skip {
+			return readCloser{body, dr}, nil
+		}
+	}
+}
+
+// openAt opens the file name at the offset off.
+func openAt(name string, off int64) (*os.File, *counter, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, &counter{f, off}, nil
+}
+
+// counter tells the offset of what is read through it.
+type counter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// offset tells the offset of what br, reading from c, gives next.
+func offset(c *counter, br *bufio.Reader) int64 {
+	return c.n - int64(br.Buffered())
+}
+
+// warcDocs reads the successful HTML responses and HTML resources
+// of a WARC file, possibly gzipped record by record. Its documents
+// are marked by their records, or by the gzip members holding them.
+type warcDocs struct {
+	io.Closer
+	c     *counter
+	fb    *bufio.Reader // the gzip members
+	zr    *gzip.Reader  // the current member
+	br    *bufio.Reader
+	block *io.LimitedReader
+
+	plain bool // uncompressed
+	base  int64
+	n     int // documents read from base
+	last  mark
+}
+
+func openWARC(name string, m mark) (docReader, error) {
+	f, c, err := openAt(name, m.off)
+	if err != nil {
+		return nil, err
+	}
+	w := &warcDocs{Closer: f, c: c, base: m.off}
+	switch compression(name) {
+	case "":
+		w.plain = true
+		w.br = bufio.NewReader(c)
+	case "gzip":
+		// The first member is started by record, as are the others.
+		w.fb = bufio.NewReader(c)
+		w.zr = new(gzip.Reader)
+		w.br = bufio.NewReader(bytes.NewReader(nil))
+	default:
+		r, err := decompress(name, f)
+		if err != nil {
+			return nil, err
+		}
+		w.Closer = r
+		w.br = bufio.NewReader(r)
+	}
+	return w, nil
+}
+
+func (w *warcDocs) next() (url, ct string, body io.Reader, err error) {
+	for {
+		h, block, err := w.record()
+		if err != nil {
+			return "", "", nil, err
+		}
+		url := strings.Trim(h.Get("WARC-Target-URI"), "<>")
+		switch h.Get("WARC-Type") {
+		case "response":
+			ct, body, err := httpResponse(block)
+			if err != nil {
+				return "", "", nil, fmt.Errorf("%s: %w", url, err)
+			}
+			if body != nil {
+				w.found()
+				return url, ct, body, nil
+			}
+		case "resource":
+			if ct := h.Get("Content-Type"); isHTMLType(ct) {
+				w.found()
+				return url, ct, block, nil
+			}
+		}
+	}
+}
+
+func (w *warcDocs) found() {
+	w.last = mark{w.base, w.n}
+	w.n++
+}
+
+func (w *warcDocs) mark() mark { return w.last }
+
+// record reads the header of the next record, skipping the rest
+// of the previous one, and gives its block.
+func (w *warcDocs) record() (textproto.MIMEHeader, io.Reader, error) {
+	if w.block != nil {
+		if _, err := io.Copy(io.Discard, w.block); err != nil {
+			return nil, nil, err
+		}
+		if w.block.N > 0 {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+	}
+	for {
+		var start int64
+		if w.plain {
+			start = offset(w.c, w.br)
+		}
+		line, err := w.br.ReadString('\n')
+		if err == io.EOF && line == "" && w.zr != nil {
+			if err := w.member(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if err == io.EOF && line == "" {
+			return nil, nil, io.EOF
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue // record separator
+		}
+		if !strings.HasPrefix(line, "WARC/") {
+			return nil, nil, fmt.Errorf("bad WARC record start %q", strings.TrimSpace(line))
+		}
+		if w.plain {
+			w.base, w.n = start, 0
+		}
+		break
+	}
+
+	h, err := textproto.NewReader(w.br).ReadMIMEHeader()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	size, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad WARC Content-Length: %w", err)
+	}
+	if size < 0 {
+		return nil, nil, fmt.Errorf("bad WARC Content-Length: %d", size)
+	}
+	w.block = &io.LimitedReader{R: w.br, N: size}
+	return h, w.block, nil
+}
+
+// member starts reading the next gzip member, io.EOF if there is none.
+func (w *warcDocs) member() error {
+	if _, err := w.fb.Peek(1); err != nil {
+		return err
+	}
+	w.base, w.n = offset(w.c, w.fb), 0
+	if err := w.zr.Reset(w.fb); err != nil {
+		return err
+	}
+	w.zr.Multistream(false)
+	w.br.Reset(w.zr)
+	return nil
+}
+
+// httpResponse reads an HTTP response, giving its content type
+// and body if it is a successful one having an HTML body.
+func httpResponse(r io.Reader) (ct string, body io.Reader, err error) {
+	resp, err := http.ReadResponse(bufio.NewReader(r), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	ct = resp.Header.Get("Content-Type")
+	if resp.StatusCode/100 != 2 || !isHTMLType(ct) {
+		return "", nil, nil
+	}
+	body, err = decodeContent(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	return ct, body, nil
+}
+
+func decodeContent(enc string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(enc) {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %s", enc)
+}
+
+type harEntry struct {
+	Request struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// harDocs reads the successful HTML responses of a HAR file
+// having their content saved, decoding the entries one by one.
+// Its documents are marked by their entries if it is uncompressed.
+type harDocs struct {
+	io.Closer
+	dec *json.Decoder
+
+	plain bool // uncompressed
+	base  int64
+	n     int // documents read
+	last  mark
+}
+
+func openHAR(name string, m mark) (docReader, error) {
+	f, c, err := openAt(name, m.off)
+	if err != nil {
+		return nil, err
+	}
+	if m.off > 0 {
+		// An entry, after the end of the previous one.
+		br := bufio.NewReader(c)
+		if err := skipSeparators(br); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return &harDocs{Closer: f, dec: json.NewDecoder(br),
+			plain: true, base: offset(c, br)}, nil
+	}
+	r, err := decompress(name, f)
+	if err != nil {
+		return nil, err
+	}
+	h := &harDocs{Closer: r, dec: json.NewDecoder(r), plain: compression(name) == ""}
+	if err := h.enter(); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return h, nil
+}
+
+// skipSeparators skips the white space and the comma before
+// an array element.
+func skipSeparators(br *bufio.Reader) error {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n', ',':
+			continue
+		}
+		return br.UnreadByte()
+	}
+}
+
+// enter moves the decoder into the log entries array,
+// or to the end if there are none.
+func (h *harDocs) enter() error {
+	for _, key := range []string{"log", "entries"} {
+		if err := h.delim('{'); err != nil {
+			return err
+		}
+		found, err := h.seek(key)
+		if err != nil {
+			return err
+		}
+		if !found {
+			h.dec = nil
+			return nil
+		}
+	}
+	return h.delim('[')
+}
+
+func (h *harDocs) delim(d json.Delim) error {
+	t, err := h.dec.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if t != d {
+		return fmt.Errorf("bad HAR file: %v instead of %v", t, d)
+	}
+	return nil
+}
+
+// seek skips the members of an object up to the value of the key,
+// telling if there is one.
+func (h *harDocs) seek(key string) (bool, error) {
+	for h.dec.More() {
+		t, err := h.dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if t == key {
+			return true, nil
+		}
+		var skip json.RawMessage
+		if err := h.dec.Decode(&skip); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (h *harDocs) next() (url, ct string, body io.Reader, err error) {
+	for h.dec != nil && h.dec.More() {
+		at := mark{0, h.n}
+		if h.plain {
+			at = mark{h.base + h.dec.InputOffset(), 0}
+		}
+		var e harEntry
+		if err := h.dec.Decode(&e); err != nil {
+			return "", "", nil, err
+		}
+		c := e.Response.Content
+		if e.Response.Status/100 != 2 || !isHTMLType(c.MimeType) || c.Text == "" {
+			continue
+		}
+		data := []byte(c.Text)
+		if c.Encoding == "base64" {
+			if data, err = base64.StdEncoding.DecodeString(c.Text); err != nil {
+				return "", "", nil, fmt.Errorf("%s: %w", e.Request.URL, err)
+			}
+		}
+		h.last = at
+		h.n++
+		return e.Request.URL, c.MimeType, bytes.NewReader(data), nil
+	}
+	return "", "", nil, io.EOF
+}
+
+func (h *harDocs) mark() mark { return h.last }
+
+// mhtmlDocs reads the HTML parts of an MHTML page save, the page
+// and its frames. Its documents are marked by their parts.
+type mhtmlDocs struct {
+	*os.File
+	c     *counter
+	br    *bufio.Reader
+	delim []byte // the delimiter line of the parts, without its line break
+	done  bool   // after the close delimiter
+	last  mark
+}
+
+func openMHTML(name string, m mark) (docReader, error) {
+	f, c, err := openAt(name, 0)
+	if err != nil {
+		return nil, err
+	}
+	md := &mhtmlDocs{File: f, c: c, br: bufio.NewReader(c)}
+	if err := md.start(m.off); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return md, nil
+}
+
+// start reads the boundary of the parts from the message header,
+// and moves to the part at the offset off, or to the first one.
+func (m *mhtmlDocs) start(off int64) error {
+	h, err := textproto.NewReader(m.br).ReadMIMEHeader()
+	if err != nil {
+		return err
+	}
+	mt, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(mt, "multipart/") {
+		return errors.New("not a multipart archive: " + mt)
+	}
+	if params["boundary"] == "" {
+		return errors.New("no multipart boundary")
+	}
+	m.delim = []byte("--" + params["boundary"])
+	if off == 0 {
+		_, err := m.part() // the preamble
+		return err
+	}
+	if _, err := m.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	m.c.n = off
+	m.br.Reset(m.c)
+	return nil
+}
+
+func (m *mhtmlDocs) next() (url, ct string, body io.Reader, err error) {
+	for !m.done {
+		at := offset(m.c, m.br)
+		h, err := textproto.NewReader(m.br).ReadMIMEHeader()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+		data, err := m.part()
+		if err != nil {
+			return "", "", nil, err
+		}
+		ct := h.Get("Content-Type")
+		if !isHTMLType(ct) {
+			continue
+		}
+		var r io.Reader = bytes.NewReader(data)
+		switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+		case "base64":
+			r = base64.NewDecoder(base64.StdEncoding, r)
+		case "quoted-printable":
+			r = quotedprintable.NewReader(r)
+		}
+		m.last = mark{at, 0}
+		return h.Get("Content-Location"), ct, r, nil
+	}
+	return "", "", nil, io.EOF
+}
+
+// part reads up to the next delimiter line, giving what is before it.
+func (m *mhtmlDocs) part() ([]byte, error) {
+	var b []byte
+	for {
+		line, err := m.br.ReadBytes('\n')
+		rest, ok := bytes.CutPrefix(bytes.TrimRight(line, " \t\r\n"), m.delim)
+		if ok && (len(rest) == 0 || string(rest) == "--") {
+			m.done = len(rest) > 0
+			// The line break before a delimiter belongs to it.
+			b = bytes.TrimSuffix(b, []byte("\n"))
+			return bytes.TrimSuffix(b, []byte("\r")), nil
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, line...)
+	}
+}
+
+func (m *mhtmlDocs) mark() mark { return m.last }
+
+func isHTMLType(ct string) bool {
+	mt, _, _ := mime.ParseMediaType(ct)
+	return mt == "text/html" || mt == "application/xhtml+xml"
+}
@@ -0,0 +1,259 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	p "github.com/wkhere/htmlx/pred"
+)
+
+func warcRecord(typ, url, contentType, block string) string {
+	return fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\nWARC-Target-URI: <%s>\r\n"+
+		"Content-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		typ, url, contentType, len(block), block)
+}
+
+const httpType = "application/http; msgtype=response"
+
+func TestWebArchives(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("<p>zipped"))
+	zw.Close()
+
+	var warc bytes.Buffer
+	for _, rec := range []string{
+		warcRecord("warcinfo", "", "application/warc-fields", "software: test\r\n"),
+		warcRecord("request", "http://a.test/", "application/http; msgtype=request",
+			"GET / HTTP/1.1\r\nHost: a.test\r\n\r\n"),
+		warcRecord("response", "http://a.test/", httpType,
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=iso-8859-2\r\n\r\n<p>\xb1"),
+		warcRecord("response", "http://a.test/gone", httpType,
+			"HTTP/1.1 404 Not Found\r\nContent-Type: text/html\r\n\r\n<p>gone"),
+		warcRecord("response", "http://a.test/img", httpType,
+			"HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\nPNG"),
+		warcRecord("response", "http://a.test/gz", httpType,
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nContent-Encoding: gzip\r\n"+
+				"Transfer-Encoding: chunked\r\n\r\n"+
+				fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", gz.Len(), gz.String())),
+		warcRecord("resource", "http://a.test/res", "text/html", "<p>resource"),
+	} {
+		// A gzip member per record.
+		zw := gzip.NewWriter(&warc)
+		zw.Write([]byte(rec))
+		zw.Close()
+	}
+
+	har := `{"log": {"entries": [
+		{"request": {"url": "http://h.test/"},
+		 "response": {"status": 200, "content": {"mimeType": "text/html", "text": "<p>plain"}}},
+		{"request": {"url": "http://h.test/b64"},
+		 "response": {"status": 200, "content": {"mimeType": "text/html; charset=utf-8",
+			"text": "PHA+YmFzZTY0", "encoding": "base64"}}},
+		{"request": {"url": "http://h.test/style.css"},
+		 "response": {"status": 200, "content": {"mimeType": "text/css", "text": "p{}"}}},
+		{"request": {"url": "http://h.test/empty"},
+		 "response": {"status": 200, "content": {"mimeType": "text/html"}}}
+	]}}`
+
+	mhtml := strings.ReplaceAll(`From: <Saved by Blink>
+Subject: page
+MIME-Version: 1.0
+Content-Type: multipart/related;
+	type="text/html";
+	boundary="----B"
+
+------B
+Content-Type: text/html
+Content-Transfer-Encoding: quoted-printable
+Content-Location: http://m.test/
+
+<p class=3D"x">quoted=
+ printable
+
+------B
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Location: http://m.test/a.png
+
+UE5H
+
+------B
+Content-Type: text/html
+Content-Transfer-Encoding: base64
+Content-Location: http://m.test/frame
+
+PHA+ZnJh
+bWU=
+
+------B--
+`, "\n", "\r\n")
+
+	tab := []struct {
+		file string
+		data []byte
+		exp  []string // url=text
+	}{
+		{"crawl.warc.gz", warc.Bytes(), []string{
+			"http://a.test/=ą",
+			"http://a.test/gz=zipped",
+			"http://a.test/res=resource",
+		}},
+		{"qa.har", []byte(har), []string{
+			"http://h.test/=plain",
+			"http://h.test/b64=base64",
+		}},
+		{"page.mhtml", []byte(mhtml), []string{
+			"http://m.test/=quoted printable",
+			"http://m.test/frame=frame",
+		}},
+	}
+
+	for i, tc := range tab {
+		path := write(tc.file, tc.data)
+		list, err := (Options{}).List(path)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		var res []string
+		for _, in := range list {
+			if in.Name != path+":"+in.URL {
+				t.Errorf("tc[%d]: bad name %s", i, in.Name)
+			}
+			f, err := in.Finder()
+			if err != nil {
+				t.Errorf("tc[%d]: %s: %v", i, in.Name, err)
+				continue
+			}
+			text := strings.TrimSpace(f.Find(p.IsText()).Data)
+			res = append(res, in.URL+"="+text)
+		}
+		if !slices.Equal(res, tc.exp) {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i,
+				strings.Join(res, "\n"), strings.Join(tc.exp, "\n"))
+		}
+	}
+
+	for _, tc := range []struct{ file, data string }{
+		{"bad.warc", "HTTP/1.1 200 OK\r\n"},
+		{"bad.har", "{"},
+		{"bad.mhtml", "Content-Type: text/html\r\n\r\n<p>"},
+		{"negative.warc", "WARC/1.0\r\nWARC-Type: resource\r\nContent-Length: -1\r\n\r\n"},
+		{"huge.warc", "WARC/1.0\r\nWARC-Type: resource\r\n" +
+			"Content-Length: 9223372036854775807\r\n\r\n<p>short"},
+		{"cut-header.warc", warcRecord("resource", "http://a.test/", "text/html", "<p>")[:60]},
+		{"cut-block.warc", warcRecord("resource", "http://a.test/", "text/html", "<p>")[:114]},
+	} {
+		if _, err := (Options{}).List(write(tc.file, []byte(tc.data))); err == nil {
+			t.Errorf("%s: expected error", tc.file)
+		}
+	}
+}
+
+func TestWebArchiveOpen(t *testing.T) {
+	const n = 300
+	url := func(i int) string { return fmt.Sprintf("http://a.test/%d", i) }
+	text := func(i int) string { return fmt.Sprintf("<p>%d", i) }
+	gzipped := func(data string) string {
+		var b strings.Builder
+		zw := gzip.NewWriter(&b)
+		io.WriteString(zw, data)
+		zw.Close()
+		return b.String()
+	}
+
+	var warc, warcGz, har, mhtml strings.Builder
+	har.WriteString(`{"log": {"entries": [`)
+	mhtml.WriteString("Content-Type: multipart/related; boundary=B\r\n\r\n")
+	for i := range n {
+		rec := warcRecord("resource", url(i), "text/html", text(i))
+		warc.WriteString(rec)
+		warcGz.WriteString(gzipped(rec))
+		if i > 0 {
+			har.WriteString(",\n")
+		}
+		fmt.Fprintf(&har, `{"request": {"url": %q}, "response": {"status": 200,`+
+			` "content": {"mimeType": "text/html", "text": %q}}}`, url(i), text(i))
+		fmt.Fprintf(&mhtml, "--B\r\nContent-Type: text/html\r\n"+
+			"Content-Location: %s\r\n\r\n%s\r\n", url(i), text(i))
+	}
+	har.WriteString("]}}")
+	mhtml.WriteString("--B--\r\n")
+
+	wipe := func(data []byte) { copy(data, bytes.Repeat([]byte("x"), 40)) }
+
+	// After listing, the start of an archive is spoiled so that reading
+	// it again up to a document fails, or gives another one; only
+	// a document read directly where it was listed opens as it was.
+	tab := []struct {
+		file  string
+		data  string
+		spoil func([]byte)
+	}{
+		{"crawl.warc", warc.String(), wipe},
+		{"crawl.warc.gz", warcGz.String(), wipe},
+		{"qa.har", har.String(), wipe},
+		{"page.mhtml", mhtml.String(), func(data []byte) {
+			i := bytes.Index(data, []byte("--B\r\n"))
+			copy(data[i:], "--X")
+		}},
+		{"whole.warc.gz", gzipped(warc.String()), nil},
+	}
+
+	for i, tc := range tab {
+		path := filepath.Join(t.TempDir(), tc.file)
+		data := []byte(tc.data)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		list, err := (Options{}).List(path)
+		if err != nil {
+			t.Fatalf("tc[%d]: %v", i, err)
+		}
+		if len(list) != n {
+			t.Fatalf("tc[%d]: %d documents listed", i, len(list))
+		}
+		if tc.spoil != nil {
+			tc.spoil(data)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			list = list[1:]
+		}
+		var res, exp []string
+		for _, in := range slices.Backward(list) {
+			r, err := in.Open()
+			if err != nil {
+				t.Errorf("tc[%d]: %s: %v", i, in.Name, err)
+				break
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			res = append(res, in.URL+"="+string(data))
+			var j int
+			fmt.Sscanf(in.URL, "http://a.test/%d", &j)
+			exp = append(exp, url(j)+"="+text(j))
+		}
+		if !slices.Equal(res, exp) {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i,
+				strings.Join(res, "\n"), strings.Join(exp, "\n"))
+		}
+	}
+}
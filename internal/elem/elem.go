@@ -0,0 +1,130 @@
+// Package elem classifies HTML elements the way serializers need:
+// inline vs block, void, raw text and whitespace-sensitive ones.
+package elem
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Block reports elements around which whitespace is insignificant.
+func Block(n *html.Node) bool {
+	return n.Type == html.ElementNode && !Inline(n)
+}
+
+// Inline reports nodes taking part in inline formatting context,
+// around which whitespace is significant.
+// Foreign, unknown and custom elements are inline.
+func Inline(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return n.Type != html.DocumentNode
+	}
+	if n.Namespace != "" {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.A, atom.Abbr, atom.Acronym, atom.Audio, atom.B, atom.Bdi,
+		atom.Bdo, atom.Big, atom.Br, atom.Button, atom.Canvas, atom.Cite,
+		atom.Code, atom.Data, atom.Del, atom.Dfn, atom.Em, atom.Embed,
+		atom.Font, atom.I, atom.Iframe, atom.Img, atom.Input, atom.Ins,
+		atom.Kbd, atom.Label, atom.Map, atom.Mark, atom.Meter, atom.Nobr,
+		atom.Noscript, atom.Object, atom.Output, atom.Picture,
+		atom.Progress, atom.Q, atom.Rp, atom.Rt, atom.Ruby, atom.S,
+		atom.Samp, atom.Script, atom.Select, atom.Slot, atom.Small,
+		atom.Span, atom.Strike, atom.Strong, atom.Style, atom.Sub, atom.Sup,
+		atom.Template, atom.Textarea, atom.Time, atom.Tt, atom.U, atom.Var,
+		atom.Video, atom.Wbr:
+		return true
+	}
+	return n.DataAtom == 0
+}
+
+// Void reports elements having no end tag.
+func Void(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Namespace != "" {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Area, atom.Base, atom.Br, atom.Col, atom.Embed, atom.Hr,
+		atom.Img, atom.Input, atom.Keygen, atom.Link, atom.Meta,
+		atom.Param, atom.Source, atom.Track, atom.Wbr:
+		return true
+	}
+	return false
+}
+
+// RawText reports elements whose text children are serialized
+// unescaped, as html.Render does.
+func RawText(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode || n.Namespace != "" {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Iframe, atom.Noembed, atom.Noframes, atom.Noscript,
+		atom.Plaintext, atom.Script, atom.Style, atom.Xmp:
+		return true
+	}
+	return false
+}
+
+// Preserved reports whether whitespace in the text of n, or of its
+// ancestors, must be kept as is.
+func Preserved(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if n.Namespace != "" {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.Pre, atom.Textarea, atom.Listing, atom.Plaintext,
+			atom.Script, atom.Style, atom.Title, atom.Xmp:
+			return true
+		}
+	}
+	return false
+}
+
+// Structural reports elements where text other than whitespace
+// is not expected, so whitespace inside can always go.
+func Structural(n *html.Node) bool {
+	if n.Type == html.DocumentNode {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Html, atom.Head, atom.Table, atom.Thead, atom.Tbody,
+		atom.Tfoot, atom.Tr, atom.Colgroup, atom.Ul, atom.Ol, atom.Dl,
+		atom.Select, atom.Datalist, atom.Optgroup, atom.Frameset,
+		atom.Menu:
+		return n.Namespace == ""
+	}
+	return false
+}
+
+// Conditional reports IE conditional comments.
+func Conditional(n *html.Node) bool {
+	return n.Type == html.CommentNode &&
+		(strings.HasPrefix(n.Data, "[if ") || strings.HasPrefix(n.Data, "[if(") ||
+			strings.HasSuffix(n.Data, "<![endif]") || strings.HasPrefix(n.Data, "<![endif]"))
+}
+
+// BooleanAttr reports attributes whose presence alone matters.
+func BooleanAttr(key string) bool {
+	return booleanAttr[key]
+}
+
+var booleanAttr = map[string]bool{}
+
+func init() {
+	for _, a := range strings.Fields(`
+		allowfullscreen async autofocus autoplay checked controls default
+		defer disabled formnovalidate hidden inert ismap itemscope loop
+		multiple muted nomodule novalidate open playsinline readonly
+		required reversed selected shadowrootclonable
+		shadowrootdelegatesfocus`) {
+		booleanAttr[a] = true
+	}
+}
@@ -0,0 +1,87 @@
+// Package extract holds what the extracting tools share: the text
+// of a subtree and the JSON objects keeping their keys in order.
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// Content concatenates the text of the subtree, like the DOM
+// textContent, skipping scripts, styles and templates.
+func Content(n *html.Node) string {
+	var b strings.Builder
+	content(&b, n, false)
+	return b.String()
+}
+
+// Text returns the words of the subtree content separated by single
+// spaces, with the blocks and line breaks separating words too.
+func Text(n *html.Node) string {
+	var b strings.Builder
+	content(&b, n, true)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func content(b *strings.Builder, n *html.Node, words bool) {
+	switch {
+	case n.Type == html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case n.DataAtom == atom.Script, n.DataAtom == atom.Style,
+		n.DataAtom == atom.Template:
+		return
+	}
+	sep := words && (elem.Block(n) || n.DataAtom == atom.Br)
+	if sep {
+		b.WriteByte(' ')
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		content(b, c, words)
+	}
+	if sep {
+		b.WriteByte(' ')
+	}
+}
+
+// Object is a JSON object keeping the members in order.
+type Object []Member
+
+type Member struct {
+	Key string
+	Val any
+}
+
+func (o Object) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, m := range o {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		k, _ := Marshal(m.Key)
+		v, err := Marshal(m.Val)
+		if err != nil {
+			return nil, err
+		}
+		b = append(append(append(b, k...), ':'), v...)
+	}
+	return append(b, '}'), nil
+}
+
+// Marshal is json.Marshal keeping <, > and & as they are,
+// for the extracted HTML to stay readable.
+func Marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,61 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestText(t *testing.T) {
+	tab := []struct {
+		html    string
+		text    string
+		content string
+	}{
+		{"<p> a\n\t b </p>", "a b", " a\n\t b "},
+		{"<p>a<b>b</b>c</p>", "abc", "abc"},
+		{"<p>a<br>b</p>", "a b", "ab"},
+		{"<ul><li>a</li><li>b</li></ul>", "a b", "ab"},
+		{"<div>a<style>x</style><template>t</template><p>b</p></div>", "a b", "ab"},
+		{"<p>a<script>x</script></p>", "a", "a"},
+		{"<p></p>", "", ""},
+	}
+	for i, tc := range tab {
+		root, err := html.Parse(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := root.FirstChild.LastChild
+		if res := Text(body); res != tc.text {
+			t.Errorf("tc[%d] text mismatch:\ngot:\n%q\nexp:\n%q", i, res, tc.text)
+		}
+		if res := Content(body); res != tc.content {
+			t.Errorf("tc[%d] content mismatch:\ngot:\n%q\nexp:\n%q", i, res, tc.content)
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	tab := []struct {
+		obj Object
+		exp string
+	}{
+		{Object{}, `{}`},
+		{Object{{"z", 1}, {"a", nil}}, `{"z":1,"a":null}`},
+		{Object{{"<k>", `<a href="/x?a&b">x</a>`}},
+			`{"<k>":"<a href=\"/x?a&b\">x</a>"}`},
+		{Object{{"in", Object{{"b", []any{true}}, {"a", "&"}}}},
+			`{"in":{"b":[true],"a":"&"}}`},
+	}
+	for i, tc := range tab {
+		res, err := Marshal(tc.obj)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		if string(res) != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
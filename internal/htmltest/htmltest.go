@@ -0,0 +1,113 @@
+// Package htmltest provides helpers for testing HTML serializers.
+package htmltest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// Equivalent checks that both documents parse to equivalent trees:
+// same elements with the same attributes, same conditional comments
+// and the same text, up to whitespace which is insignificant around
+// block elements, and collapsible elsewhere.
+// Other comments are ignored.
+func Equivalent(a, b string) error {
+	return equivalent(a, b, false)
+}
+
+// EquivalentLayout is like Equivalent, but the whitespace around line
+// breaks is insignificant too, as it ends up at the line end or start;
+// it is for checking serializers which break lines after <br>.
+func EquivalentLayout(a, b string) error {
+	return equivalent(a, b, true)
+}
+
+func equivalent(a, b string, looseBreaks bool) error {
+	ta, err := html.Parse(strings.NewReader(a))
+	if err != nil {
+		return err
+	}
+	tb, err := html.Parse(strings.NewReader(b))
+	if err != nil {
+		return err
+	}
+	sa, sb := flatten(ta, looseBreaks), flatten(tb, looseBreaks)
+	if sa != sb {
+		return fmt.Errorf("trees differ:\n%s\n%s", sa, sb)
+	}
+	return nil
+}
+
+var (
+	blockSpaceRe = regexp.MustCompile(`[ \t\n\f\r]*\x00[ \t\n\f\r\x00]*`)
+	spaceRe      = regexp.MustCompile(`[ \t\n\f\r]+`)
+)
+
+// Flatten serializes the tree into a normalized form, one block
+// boundary per line, for comparisons.
+func Flatten(root *html.Node) string {
+	return flatten(root, false)
+}
+
+func flatten(root *html.Node, looseBreaks bool) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		block := elem.Block(n) || looseBreaks && n.DataAtom == atom.Br
+		switch n.Type {
+		case html.TextNode:
+			switch {
+			case elem.Preserved(n):
+				b.WriteString(strings.ReplaceAll(n.Data, " ", "\x01"))
+			case elem.Structural(n.Parent) && strings.TrimSpace(n.Data) == "":
+			default:
+				b.WriteString(n.Data)
+			}
+		case html.CommentNode:
+			if elem.Conditional(n) {
+				b.WriteString("<!--" + n.Data + "-->")
+			}
+		case html.DoctypeNode:
+			b.WriteString("\x00<!doctype " + n.Data + ">\x00")
+		case html.ElementNode:
+			if block {
+				b.WriteByte(0)
+			}
+			b.WriteString("<" + n.Namespace + ":" + n.Data)
+			for _, a := range n.Attr {
+				v := a.Val
+				if elem.BooleanAttr(a.Key) && strings.EqualFold(v, a.Key) {
+					v = ""
+				}
+				fmt.Fprintf(&b, " %s:%s=%q", a.Namespace, a.Key, v)
+			}
+			b.WriteString(">")
+			if block {
+				b.WriteByte(0)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			if block {
+				b.WriteByte(0)
+			}
+			b.WriteString("</" + n.Data + ">")
+			if block {
+				b.WriteByte(0)
+			}
+		}
+	}
+	walk(root)
+
+	s := blockSpaceRe.ReplaceAllString(b.String(), "\x00")
+	s = spaceRe.ReplaceAllString(s, " ")
+	return strings.NewReplacer("\x00", "\n", "\x01", " ").Replace(s)
+}
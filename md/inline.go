@@ -0,0 +1,252 @@
+package md
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/attr"
+)
+
+// inline accumulates inline content, collapsing whitespace.
+type inline struct {
+	buf     []byte
+	space   bool // last written byte is a space, or nothing written yet
+	oneLine bool // hard breaks become spaces, as in headings
+	table   bool // hard breaks become <br>, as in table cells
+}
+
+func (in *inline) text(s string) {
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if !in.space {
+				in.buf = append(in.buf, ' ')
+				in.space = true
+			}
+			continue
+		}
+		in.space = false
+		switch {
+		case r == '&' && entityRe.MatchString(s[i:]):
+			in.buf = append(in.buf, `\&`...)
+		case r == '|' && in.table:
+			in.buf = append(in.buf, `\|`...)
+		case strings.ContainsRune("\\`*_[]<~", r):
+			in.buf = append(in.buf, '\\', byte(r))
+		default:
+			in.buf = append(in.buf, string(r)...)
+		}
+	}
+}
+
+func (in *inline) raw(s string) {
+	if s == "" {
+		return
+	}
+	if s[0] == '[' && len(in.buf) > 0 && in.buf[len(in.buf)-1] == '!' {
+		// The ! would make the link an image.
+		in.buf = append(in.buf[:len(in.buf)-1], `\!`...)
+	}
+	in.buf = append(in.buf, s...)
+	in.space = s[len(s)-1] == ' ' || s[len(s)-1] == '\n'
+}
+
+func (in *inline) hardBreak() {
+	switch {
+	case in.oneLine:
+		in.text(" ")
+	case in.table:
+		in.raw("<br>")
+	default:
+		in.buf = []byte(strings.TrimRight(string(in.buf), " "))
+		if len(in.buf) > 0 {
+			in.raw("\\\n")
+		}
+	}
+}
+
+func (in *inline) String() string {
+	s := strings.Trim(string(in.buf), " ")
+	for strings.HasSuffix(s, "\\\n") {
+		s = strings.TrimRight(strings.TrimSuffix(s, "\\\n"), " ")
+	}
+	return strings.ReplaceAll(s, "\n ", "\n")
+}
+
+var entityRe = regexp.MustCompile(`^&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+
+func para(in *inline) []block {
+	s := in.String()
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = escapeLineStart(l)
+	}
+	return []block{{paraBlock, strings.Join(lines, "\n")}}
+}
+
+var lineStartRe = regexp.MustCompile(`^(?:#{1,6}(?:\s|$)|[-+](?:\s|$)|>|=+\s*$|-{2,})`)
+var orderedRe = regexp.MustCompile(`^(\d{1,9})([.)])(\s|$)`)
+
+// escapeLineStart escapes text which would otherwise start
+// a heading, a list item, a quote or a setext underline.
+func escapeLineStart(s string) string {
+	if lineStartRe.MatchString(s) {
+		return `\` + s
+	}
+	return orderedRe.ReplaceAllString(s, `$1\$2$3`)
+}
+
+func (c *conv) children(in *inline, n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.inline(in, ch)
+	}
+}
+
+func (c *conv) inline(in *inline, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		in.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		in.hardBreak()
+	case atom.Em, atom.I, atom.Cite, atom.Var, atom.Dfn:
+		c.wrap(in, n, "*")
+	case atom.Strong, atom.B:
+		c.wrap(in, n, "**")
+	case atom.Del, atom.S, atom.Strike:
+		c.wrap(in, n, "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		in.raw(codeSpan(textOf(n)))
+	case atom.A:
+		c.link(in, n)
+	case atom.Img:
+		c.image(in, n)
+	default:
+		switch {
+		case skipped(n):
+		case unsupported(n) && c.StripUnsupported:
+		case unsupported(n):
+			in.raw(rawHTML(n))
+		default:
+			c.children(in, n)
+		}
+	}
+}
+
+// wrap surrounds inline content with delimiters, keeping outer
+// whitespace outside, as CommonMark requires.
+func (c *conv) wrap(in *inline, n *html.Node, delim string) {
+	sub := &inline{space: in.space, oneLine: in.oneLine, table: in.table}
+	c.children(sub, n)
+	s := string(sub.buf)
+	core := strings.Trim(s, " ")
+	if core == "" {
+		in.raw(s)
+		return
+	}
+	if strings.HasPrefix(s, " ") {
+		in.raw(" ")
+	}
+	in.raw(delim + core + delim)
+	if strings.HasSuffix(s, " ") {
+		in.raw(" ")
+	}
+}
+
+func (c *conv) link(in *inline, n *html.Node) {
+	a := attr.L(n.Attr)
+	href, ok := a.Val("href")
+	if !ok {
+		c.children(in, n)
+		return
+	}
+	u := c.resolve(href)
+	title, _ := a.Val("title")
+
+	sub := &inline{space: in.space, oneLine: in.oneLine, table: in.table}
+	c.children(sub, n)
+	text := sub.String()
+	lead := strings.HasPrefix(string(sub.buf), " ")
+	trail := strings.HasSuffix(string(sub.buf), " ")
+
+	if title == "" && text != "" && textOf(n) == u &&
+		(strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")) &&
+		!strings.ContainsAny(u, " <>") {
+		in.raw("<" + u + ">")
+		return
+	}
+	if lead {
+		in.raw(" ")
+	}
+	if c.LinkStyle == Reference {
+		in.raw("[" + text + "][" + strconv.Itoa(c.ref(u, title)) + "]")
+	} else {
+		in.raw("[" + text + "](" + dest(u) + titlePart(title) + ")")
+	}
+	if trail {
+		in.raw(" ")
+	}
+}
+
+func (c *conv) image(in *inline, n *html.Node) {
+	a := attr.L(n.Attr)
+	src, ok := a.Val("src")
+	if !ok {
+		return
+	}
+	u := c.resolve(src)
+	alt, _ := a.Val("alt")
+	title, _ := a.Val("title")
+
+	sub := &inline{space: true}
+	sub.text(alt)
+	alt = sub.String()
+
+	if c.LinkStyle == Reference {
+		in.raw("![" + alt + "][" + strconv.Itoa(c.ref(u, title)) + "]")
+	} else {
+		in.raw("![" + alt + "](" + dest(u) + titlePart(title) + ")")
+	}
+}
+
+func codeSpan(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if s == "" {
+		return ""
+	}
+	delim := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") ||
+		(strings.HasPrefix(s, " ") && strings.HasSuffix(s, " ") &&
+			strings.TrimSpace(s) != "") {
+		s = " " + s + " "
+	}
+	return delim + s + delim
+}
+
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
@@ -0,0 +1,402 @@
+// Package md converts HTML subtrees into CommonMark,
+// with GFM extensions for tables and strikethrough.
+package md
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+)
+
+type LinkStyle int
+
+const (
+	// Inline links look like [text](url).
+	Inline LinkStyle = iota
+	// Reference links look like [text][1], with the definitions
+	// listed at the end of the output.
+	Reference
+)
+
+// Converter holds conversion options.
+// The zero value produces inline links, leaves URLs as they are and keeps
+// elements having no Markdown counterpart, like <video>, as raw HTML.
+type Converter struct {
+	LinkStyle LinkStyle
+
+	// Base, if set, is used to resolve relative link and image URLs.
+	Base *url.URL
+
+	// StripUnsupported drops elements having no Markdown counterpart
+	// instead of passing them through as raw HTML.
+	StripUnsupported bool
+}
+
+// String converts the subtree using default options.
+func String(f htmlx.Finder) string {
+	var b strings.Builder
+	Converter{}.Convert(&b, f)
+	return b.String()
+}
+
+// Convert writes the subtree rooted at f as Markdown.
+func (c Converter) Convert(w io.Writer, f htmlx.Finder) error {
+	if f.IsEmpty() {
+		return nil
+	}
+	cv := &conv{Converter: c, refIdx: map[string]int{}}
+
+	var bb []block
+	switch n := f.Node; {
+	case n.Type != html.ElementNode:
+		bb = cv.blocks(n)
+	case skipped(n):
+	case isBlock(n):
+		bb = cv.block(n)
+	default:
+		in := &inline{space: true}
+		cv.inline(in, n)
+		bb = para(in)
+	}
+
+	s := joinBlocks(bb, "\n\n")
+	if len(cv.refs) > 0 {
+		var b strings.Builder
+		for i, r := range cv.refs {
+			fmt.Fprintf(&b, "[%d]: %s%s\n", i+1, dest(r.url), titlePart(r.title))
+		}
+		s += "\n\n" + strings.TrimSuffix(b.String(), "\n")
+	}
+	if s == "" {
+		return nil
+	}
+	_, err := io.WriteString(w, s+"\n")
+	return err
+}
+
+type conv struct {
+	Converter
+	refs   []ref
+	refIdx map[string]int
+}
+
+type ref struct {
+	url, title string
+}
+
+type blockKind int
+
+const (
+	paraBlock blockKind = iota
+	otherBlock
+	listBlock
+)
+
+type block struct {
+	kind blockKind
+	text string
+}
+
+// blocks renders children of n, grouping runs of inline content
+// into paragraphs.
+func (c *conv) blocks(n *html.Node) (bb []block) {
+	in := &inline{space: true}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		switch {
+		case ch.Type == html.ElementNode && skipped(ch):
+		case ch.Type == html.ElementNode && isBlock(ch):
+			bb = append(bb, para(in)...)
+			in = &inline{space: true}
+			bb = append(bb, c.block(ch)...)
+		default:
+			c.inline(in, ch)
+		}
+	}
+	return append(bb, para(in)...)
+}
+
+func (c *conv) block(n *html.Node) []block {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		in := &inline{space: true, oneLine: true}
+		c.children(in, n)
+		s := in.String()
+		if s == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []block{{otherBlock, strings.Repeat("#", level) + " " + s}}
+
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+
+	case atom.Blockquote:
+		s := joinBlocks(c.blocks(n), "\n\n")
+		if s == "" {
+			return nil
+		}
+		return []block{{otherBlock, prefixLines(s, "> ", ">")}}
+
+	case atom.Pre:
+		return []block{{otherBlock, codeBlock(n)}}
+
+	case atom.Table:
+		return c.table(n)
+
+	case atom.Hr:
+		return []block{{otherBlock, "---"}}
+	}
+
+	if unsupported(n) {
+		if c.StripUnsupported {
+			return nil
+		}
+		return []block{{otherBlock, rawHTML(n)}}
+	}
+	return c.blocks(n)
+}
+
+func (c *conv) list(n *html.Node) []block {
+	start := 1
+	if s, ok := attr.L(n.Attr).Val("start"); ok {
+		if i, err := strconv.Atoi(s); err == nil {
+			start = i
+		}
+	}
+
+	var items []string
+	loose := false
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode {
+			continue
+		}
+		var bb []block
+		if li.DataAtom == atom.Li {
+			bb = c.blocks(li)
+		} else {
+			bb = c.block(li)
+		}
+
+		content := joinItem(bb)
+		if strings.Contains(content, "\n\n") {
+			loose = true
+		}
+
+		marker := "-"
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(start+len(items)) + "."
+		}
+		if content == "" {
+			items = append(items, marker)
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker)+1)
+		items = append(items, marker+" "+indentLines(content, indent))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	sep := "\n"
+	if loose {
+		sep = "\n\n"
+	}
+	return []block{{listBlock, strings.Join(items, sep)}}
+}
+
+// joinItem joins blocks of a list item, keeping a nested list
+// right after the item's text so that tight lists stay tight.
+func joinItem(bb []block) string {
+	var b strings.Builder
+	for i, x := range bb {
+		if i > 0 {
+			if x.kind == listBlock && bb[i-1].kind == paraBlock {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(x.text)
+	}
+	return b.String()
+}
+
+func codeBlock(n *html.Node) string {
+	lang := language(n)
+	if c := n.FirstChild; c != nil && c.DataAtom == atom.Code && lang == "" {
+		lang = language(c)
+	}
+
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	code := strings.TrimRight(b.String(), "\n")
+
+	fence := "```"
+	if r := longestRun(code, '`'); r >= 3 {
+		fence = strings.Repeat("`", r+1)
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+var langRe = regexp.MustCompile(`^(?:language|lang)-(.+)$`)
+
+func language(n *html.Node) string {
+	cc, _ := attr.L(n.Attr).ClassList()
+	for _, c := range cc {
+		if m := langRe.FindStringSubmatch(c); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func rawHTML(n *html.Node) string {
+	var b strings.Builder
+	html.Render(&b, n)
+	// A blank line would end the HTML block.
+	return blankLinesRe.ReplaceAllString(b.String(), "\n")
+}
+
+var blankLinesRe = regexp.MustCompile(`\n\s*\n`)
+
+func (c *conv) resolve(s string) string {
+	s = strings.TrimSpace(s)
+	if c.Base == nil {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return c.Base.ResolveReference(u).String()
+}
+
+func (c *conv) ref(u, title string) int {
+	k := u + "\x00" + title
+	if i, ok := c.refIdx[k]; ok {
+		return i
+	}
+	c.refs = append(c.refs, ref{u, title})
+	c.refIdx[k] = len(c.refs)
+	return len(c.refs)
+}
+
+func dest(u string) string {
+	if u == "" {
+		return "<>"
+	}
+	if strings.ContainsAny(u, " ()<>\t\n") {
+		r := strings.NewReplacer("<", "%3C", ">", "%3E", "\n", "%0A")
+		return "<" + r.Replace(u) + ">"
+	}
+	return u
+}
+
+func titlePart(t string) string {
+	if t == "" {
+		return ""
+	}
+	return ` "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(t) + `"`
+}
+
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Body,
+		atom.Dd, atom.Details, atom.Dialog, atom.Div, atom.Dl, atom.Dt,
+		atom.Fieldset, atom.Figcaption, atom.Figure, atom.Footer, atom.Form,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Header,
+		atom.Hgroup, atom.Hr, atom.Html, atom.Li, atom.Main, atom.Nav,
+		atom.Ol, atom.P, atom.Pre, atom.Section, atom.Summary, atom.Table,
+		atom.Ul, atom.Iframe, atom.Video, atom.Audio, atom.Canvas,
+		atom.Object, atom.Embed, atom.Svg, atom.Math:
+		return true
+	}
+	return false
+}
+
+// unsupported reports elements having no Markdown counterpart.
+func unsupported(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Iframe, atom.Video, atom.Audio, atom.Canvas, atom.Object,
+		atom.Embed, atom.Svg, atom.Math, atom.Form, atom.Input, atom.Button,
+		atom.Select, atom.Textarea:
+		return true
+	}
+	return false
+}
+
+func skipped(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Noscript,
+		atom.Title, atom.Meta, atom.Link:
+		return true
+	}
+	return false
+}
+
+func joinBlocks(bb []block, sep string) string {
+	ss := make([]string, 0, len(bb))
+	for _, b := range bb {
+		ss = append(ss, b.text)
+	}
+	return strings.Join(ss, sep)
+}
+
+func prefixLines(s, prefix, emptyPrefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines indents all lines but the first.
+func indentLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func longestRun(s string, c byte) (max int) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			n++
+			if n > max {
+				max = n
+			}
+		} else {
+			n = 0
+		}
+	}
+	return max
+}
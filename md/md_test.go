@@ -0,0 +1,91 @@
+package md
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+)
+
+func TestConvert(t *testing.T) {
+	tab := []struct {
+		html, md string
+	}{
+		{`<h2>Title <em>here</em></h2><p>Some  <b>bold </b>text.</p>`,
+			"## Title *here*\n\nSome **bold** text."},
+		{`<p>a<br>b<br></p>`, "a\\\nb"},
+		{`<p>1. not a list, *not* _em_ [x] &amp;amp; a|b</p>`,
+			`1\. not a list, \*not\* \_em\_ \[x\] \&amp; a|b`},
+		{`<p># no heading</p><p>- no item</p><p>&gt; no quote</p>`,
+			"\\# no heading\n\n\\- no item\n\n\\> no quote"},
+		{`<p>see <a href="/x" title="X">the <i>docs</i></a>, <a href="https://a.b/">https://a.b/</a></p>`,
+			`see [the *docs*](https://example.com/x "X"), <https://a.b/>`},
+		{`<img src="i.png" alt="a [b]">`, `![a \[b\]](https://example.com/i.png)`},
+		{`<a href="/a b">x</a>`, `[x](https://example.com/a%20b)`},
+		{`<a href="(x)">x</a>`, `[x](<https://example.com/(x)>)`},
+		{`<p>Hello!<a href="x">y</a> Wow! <img src="i.png" alt="i">!</p>`,
+			`Hello\![y](https://example.com/x) Wow! ![i](https://example.com/i.png)!`},
+		{`<ul><li>one</li><li>two<ul><li>sub</li></ul></li></ul>`,
+			"- one\n- two\n  - sub"},
+		{`<ol start="3"><li><p>one</p><p>more</p></li><li>two</li></ol>`,
+			"3. one\n\n   more\n\n4. two"},
+		{`<blockquote><p>q1</p><blockquote>q2</blockquote></blockquote>`,
+			"> q1\n>\n> > q2"},
+		{"<pre><code class=\"language-go\">func f() {\n\treturn\n}\n</code></pre>",
+			"```go\nfunc f() {\n\treturn\n}\n```"},
+		{"<pre>a ``` b</pre>", "````\na ``` b\n````"},
+		{"<p>use <code>a`b</code> and <code>`x</code></p>",
+			"use ``a`b`` and `` `x ``"},
+		{`<p><del>old</del> new</p>`, "~~old~~ new"},
+		{`<table><thead><tr><th>a</th><th align="right">b|c</th></tr></thead>` +
+			`<tbody><tr><td>1</td><td style="text-align: center">2<br>3</td></tr>` +
+			`<tr><td colspan="2">wide</td></tr></tbody></table>`,
+			"| a | b\\|c |\n| --- | ---: |\n| 1 | 2<br>3 |\n| wide |  |"},
+		{`<hr><div><p>x</p></div>`, "---\n\nx"},
+		{`<p>a<script>x()</script><video src="v.mp4"></video>b</p>`,
+			"a\n\n<video src=\"v.mp4\"></video>\n\nb"},
+	}
+
+	base, _ := url.Parse("https://example.com/")
+	c := Converter{Base: base}
+
+	for i, tc := range tab {
+		f, _ := htmlx.FinderFromString(tc.html)
+		var b strings.Builder
+		if err := c.Convert(&b, f); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := strings.TrimSuffix(b.String(), "\n"); res != tc.md {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.md)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	f, _ := htmlx.FinderFromString(
+		`<p><a href="a">A</a> <a href="b" title="B">B</a> <a href="a">A2</a>` +
+			` <img src="i.png" alt="I"></p><iframe src="x"></iframe>`,
+	)
+
+	c := Converter{LinkStyle: Reference, StripUnsupported: true}
+	exp := "[A][1] [B][2] [A2][1] ![I][3]\n\n" +
+		"[1]: a\n[2]: b \"B\"\n[3]: i.png\n"
+
+	var b strings.Builder
+	c.Convert(&b, f)
+	if res := b.String(); res != exp {
+		t.Errorf("mismatch:\ngot:\n%s\nexp:\n%s", res, exp)
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	f, _ := htmlx.FinderFromString(`<div><ul><li>x</li></ul><span>y</span></div>`)
+
+	if res, exp := String(f.FirstChild().LastChild().FirstChild().FirstChild()), "- x\n"; res != exp {
+		t.Errorf("got %q, exp %q", res, exp)
+	}
+	if res, exp := String(htmlx.Finder{}), ""; res != exp {
+		t.Errorf("got %q, exp %q", res, exp)
+	}
+}
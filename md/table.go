@@ -0,0 +1,143 @@
+package md
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/attr"
+)
+
+// table renders a GFM table. The first row always becomes the header,
+// as GFM tables cannot go without one.
+func (c *conv) table(n *html.Node) (bb []block) {
+	var rows [][]string
+	var aligns []string
+
+	var caption string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			switch ch.DataAtom {
+			case atom.Caption:
+				in := &inline{space: true, oneLine: true}
+				c.children(in, ch)
+				caption = in.String()
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(ch)
+			case atom.Tr:
+				row, al := c.row(ch)
+				if len(rows) == 0 {
+					aligns = al
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+
+	if caption != "" {
+		bb = append(bb, block{paraBlock, caption})
+	}
+	if len(rows) == 0 {
+		return bb
+	}
+
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+
+	var b strings.Builder
+	line := func(cells []string) {
+		b.WriteByte('|')
+		for i := range cols {
+			s := ""
+			if i < len(cells) {
+				s = cells[i]
+			}
+			b.WriteString(" " + s + " |")
+		}
+		b.WriteByte('\n')
+	}
+
+	line(rows[0])
+	delims := make([]string, cols)
+	for i := range delims {
+		al := ""
+		if i < len(aligns) {
+			al = aligns[i]
+		}
+		switch al {
+		case "left":
+			delims[i] = ":---"
+		case "center":
+			delims[i] = ":---:"
+		case "right":
+			delims[i] = "---:"
+		default:
+			delims[i] = "---"
+		}
+	}
+	line(delims)
+	for _, r := range rows[1:] {
+		line(r)
+	}
+
+	return append(bb, block{otherBlock, strings.TrimSuffix(b.String(), "\n")})
+}
+
+func (c *conv) row(tr *html.Node) (cells, aligns []string) {
+	for td := tr.FirstChild; td != nil; td = td.NextSibling {
+		if td.DataAtom != atom.Td && td.DataAtom != atom.Th {
+			continue
+		}
+		in := &inline{space: true, table: true}
+		c.cell(in, td)
+		a := attr.L(td.Attr)
+
+		span := 1
+		if s, ok := a.Val("colspan"); ok {
+			if i, err := strconv.Atoi(s); err == nil && i > 1 {
+				span = i
+			}
+		}
+		cells = append(cells, in.String())
+		aligns = append(aligns, alignment(a))
+		for range span - 1 {
+			cells = append(cells, "")
+			aligns = append(aligns, "")
+		}
+	}
+	return cells, aligns
+}
+
+// cell renders all cell content inline, separating blocks with <br>.
+func (c *conv) cell(in *inline, n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && isBlock(ch) && !unsupported(ch) {
+			if len(in.buf) > 0 {
+				in.hardBreak()
+			}
+			c.cell(in, ch)
+			continue
+		}
+		c.inline(in, ch)
+	}
+}
+
+func alignment(a attr.List) string {
+	if v, ok := a.Val("align"); ok {
+		return strings.ToLower(v)
+	}
+	style, _ := a.Val("style")
+	for _, decl := range strings.Split(style, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if ok && strings.TrimSpace(k) == "text-align" {
+			return strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+	return ""
+}
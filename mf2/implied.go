@@ -0,0 +1,197 @@
+package mf2
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/attr"
+)
+
+func (p *parser) implyProps(n *html.Node, it *Item) {
+	hasNested := len(it.Children) > 0 || hasItemValues(it)
+
+	if _, ok := it.Properties["name"]; !ok && !hasNested && !hasPrefix(n, "p", "e") {
+		it.Properties["name"] = []any{impliedName(n)}
+	}
+	if _, ok := it.Properties["photo"]; !ok && !hasNested && !hasPrefix(n, "u") {
+		if v := p.impliedPhoto(n); v != nil {
+			it.Properties["photo"] = []any{v}
+		}
+	}
+	if _, ok := it.Properties["url"]; !ok && !hasNested && !hasPrefix(n, "u") {
+		if v, ok := p.impliedURL(n); ok {
+			it.Properties["url"] = []any{v}
+		}
+	}
+}
+
+func impliedName(n *html.Node) string {
+	try := func(n *html.Node) (string, bool) {
+		a := attr.L(n.Attr)
+		switch n.DataAtom {
+		case atom.Img, atom.Area:
+			if v, ok := a.Val("alt"); ok {
+				return v, true
+			}
+		case atom.Abbr:
+			if v, ok := a.Val("title"); ok {
+				return v, true
+			}
+		}
+		return "", false
+	}
+
+	if v, ok := try(n); ok {
+		return v
+	}
+	if c := onlyChild(n); c != nil && len(rootClasses(c)) == 0 {
+		if v, ok := try(c); ok && v != "" {
+			return v
+		}
+		if cc := onlyChild(c); cc != nil && len(rootClasses(cc)) == 0 {
+			if v, ok := try(cc); ok && v != "" {
+				return v
+			}
+		}
+	}
+	return textContent(n, false, nil)
+}
+
+func (p *parser) impliedPhoto(n *html.Node) any {
+	try := func(n *html.Node) any {
+		a := attr.L(n.Attr)
+		switch n.DataAtom {
+		case atom.Img:
+			if _, ok := a.Val("src"); ok {
+				return p.uValue(n)
+			}
+		case atom.Object:
+			if v, ok := a.Val("data"); ok {
+				return p.resolve(v)
+			}
+		}
+		return nil
+	}
+
+	if v := try(n); v != nil {
+		return v
+	}
+	for _, tag := range []atom.Atom{atom.Img, atom.Object} {
+		c := onlyOfType(n, tag)
+		if c != nil && len(rootClasses(c)) == 0 {
+			if v := try(c); v != nil {
+				return v
+			}
+		}
+	}
+	if c := onlyChild(n); c != nil && len(rootClasses(c)) == 0 {
+		for _, tag := range []atom.Atom{atom.Img, atom.Object} {
+			cc := onlyOfType(c, tag)
+			if cc != nil && len(rootClasses(cc)) == 0 {
+				if v := try(cc); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) impliedURL(n *html.Node) (string, bool) {
+	try := func(n *html.Node) (string, bool) {
+		switch n.DataAtom {
+		case atom.A, atom.Area:
+			if v, ok := attr.L(n.Attr).Val("href"); ok {
+				return p.resolve(v), true
+			}
+		}
+		return "", false
+	}
+
+	if v, ok := try(n); ok {
+		return v, true
+	}
+	for _, tag := range []atom.Atom{atom.A, atom.Area} {
+		c := onlyOfType(n, tag)
+		if c != nil && len(rootClasses(c)) == 0 {
+			if v, ok := try(c); ok {
+				return v, true
+			}
+		}
+	}
+	if c := onlyChild(n); c != nil && len(rootClasses(c)) == 0 {
+		for _, tag := range []atom.Atom{atom.A, atom.Area} {
+			cc := onlyOfType(c, tag)
+			if cc != nil && len(rootClasses(cc)) == 0 {
+				if v, ok := try(cc); ok {
+					return v, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// hasPrefix reports whether any descendant of n, not inside a nested
+// microformat, has a property class with one of the given prefixes.
+func hasPrefix(n *html.Node, prefixes ...string) bool {
+	for c := range elements(n) {
+		for _, pc := range propClasses(c) {
+			for _, x := range prefixes {
+				if pc.prefix == x {
+					return true
+				}
+			}
+		}
+		if len(rootClasses(c)) == 0 && hasPrefix(c, prefixes...) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasItemValues(it *Item) bool {
+	for _, vv := range it.Properties {
+		for _, v := range vv {
+			if _, ok := v.(*Item); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// onlyChild returns the single element child of n,
+// ignoring whitespace-only text.
+func onlyChild(n *html.Node) (only *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			if only != nil {
+				return nil
+			}
+			only = c
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return nil
+			}
+		}
+	}
+	return only
+}
+
+// onlyOfType returns the single element child of n with the given tag.
+func onlyOfType(n *html.Node, tag atom.Atom) (only *html.Node) {
+	for c := range elements(n) {
+		if c.DataAtom != tag {
+			continue
+		}
+		if only != nil {
+			return nil
+		}
+		only = c
+	}
+	return only
+}
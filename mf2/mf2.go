@@ -0,0 +1,333 @@
+// Package mf2 implements the microformats2 parsing algorithm
+// (https://microformats.org/wiki/microformats2-parsing) on top of Finder.
+//
+// Only microformats2 class names are recognized; classic (backcompat)
+// roots like vcard or hentry are not.
+package mf2
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// Data is the canonical parser output, marshaling to the JSON
+// used by the microformats test suite.
+type Data struct {
+	Items   []*Item             `json:"items"`
+	Rels    map[string][]string `json:"rels"`
+	RelURLs map[string]*RelURL  `json:"rel-urls"`
+}
+
+// Item is a parsed microformat. Value is set only for items being
+// a property value of their parent item.
+type Item struct {
+	Type       []string         `json:"type"`
+	Properties map[string][]any `json:"properties"`
+	ID         string           `json:"id,omitempty"`
+	Value      any              `json:"value,omitempty"`
+	HTML       string           `json:"html,omitempty"`
+	Children   []*Item          `json:"children,omitempty"`
+
+	date string // first date seen in dt-* properties
+}
+
+type RelURL struct {
+	Rels     []string `json:"rels"`
+	Text     string   `json:"text,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Media    string   `json:"media,omitempty"`
+	HrefLang string   `json:"hreflang,omitempty"`
+	Type     string   `json:"type,omitempty"`
+}
+
+// Image is the value of u-photo and similar properties
+// when the image has an alt attribute.
+type Image struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+// Embedded is the value of an e-* property.
+type Embedded struct {
+	HTML  string `json:"html"`
+	Value string `json:"value"`
+}
+
+var (
+	rootRe = regexp.MustCompile(`^h-(?:[a-z0-9]+-)?[a-z]+(?:-[a-z]+)*$`)
+	propRe = regexp.MustCompile(`^(p|u|dt|e)-((?:[a-z0-9]+-)?[a-z]+(?:-[a-z]+)*)$`)
+)
+
+// IsRootClass reports whether the class name is a microformats2 root,
+// like h-entry.
+func IsRootClass(class string) bool {
+	return rootRe.MatchString(class)
+}
+
+// IsPropertyClass reports whether the class name is a microformats2
+// property, like p-name or dt-published.
+func IsPropertyClass(class string) bool {
+	return propRe.MatchString(class)
+}
+
+// Root is a predicate matching elements being microformat roots.
+func Root() pred.Predicate {
+	return pred.ClassCond(IsRootClass)
+}
+
+type parser struct {
+	base *url.URL
+}
+
+// Parse parses all microformats found in the document or subtree.
+// The base URL, if not nil, is used to resolve relative URLs;
+// a <base href> found in the document takes precedence.
+func Parse(f htmlx.Finder, base *url.URL) *Data {
+	p := &parser{base: base}
+
+	d := &Data{
+		Items:   []*Item{},
+		Rels:    map[string][]string{},
+		RelURLs: map[string]*RelURL{},
+	}
+	if f.IsEmpty() {
+		return d
+	}
+
+	if b := f.Find(pred.Element(atom.Base, pred.AttrCond("href", nonEmpty))); !b.IsEmpty() {
+		href, _ := b.Attr().Val("href")
+		p.base = p.resolveURL(href)
+	}
+
+	p.findItems(f.Node, &d.Items)
+	p.parseRels(f, d)
+	return d
+}
+
+func (p *parser) findItems(n *html.Node, items *[]*Item) {
+	for c := range elements(n) {
+		if types := rootClasses(c); len(types) > 0 {
+			*items = append(*items, p.parseItem(c, types))
+			continue
+		}
+		p.findItems(c, items)
+	}
+}
+
+func (p *parser) parseItem(n *html.Node, types []string) *Item {
+	it := &Item{
+		Type:       types,
+		Properties: map[string][]any{},
+	}
+	if id, ok := attr.L(n.Attr).ID(); ok && id != "" {
+		it.ID = id
+	}
+
+	p.parseProps(n, it)
+	p.implyProps(n, it)
+	return it
+}
+
+func (p *parser) parseProps(n *html.Node, it *Item) {
+	for c := range elements(n) {
+		props := propClasses(c)
+
+		if types := rootClasses(c); len(types) > 0 {
+			child := p.parseItem(c, types)
+			if len(props) == 0 {
+				it.Children = append(it.Children, child)
+				continue
+			}
+			for _, pc := range props {
+				v := *child
+				v.Value = p.embeddedValue(c, pc, &v)
+				it.add(pc.name, &v)
+			}
+			continue
+		}
+
+		for _, pc := range props {
+			it.add(pc.name, p.propValue(c, pc.prefix, it))
+		}
+		p.parseProps(c, it)
+	}
+}
+
+func (p *parser) embeddedValue(n *html.Node, pc propClass, child *Item) any {
+	switch pc.prefix {
+	case "p":
+		if v, ok := firstString(child.Properties["name"]); ok {
+			return v
+		}
+	case "e":
+		e := p.embedded(n)
+		child.HTML = e.HTML
+		return e.Value
+	case "u":
+		if vv := child.Properties["url"]; len(vv) > 0 {
+			switch v := vv[0].(type) {
+			case string:
+				return v
+			case *Image:
+				return v.Value
+			}
+		}
+	}
+	switch v := p.propValue(n, pc.prefix, nil).(type) {
+	case *Image:
+		return v.Value
+	default:
+		return v
+	}
+}
+
+func (p *parser) propValue(n *html.Node, prefix string, it *Item) any {
+	switch prefix {
+	case "p":
+		return p.pValue(n)
+	case "u":
+		return p.uValue(n)
+	case "dt":
+		return p.dtValue(n, it)
+	case "e":
+		return p.embedded(n)
+	}
+	return nil
+}
+
+func (it *Item) add(name string, v any) {
+	it.Properties[name] = append(it.Properties[name], v)
+}
+
+func (p *parser) parseRels(f htmlx.Finder, d *Data) {
+	isLink := func(h *html.Node) bool {
+		switch h.DataAtom {
+		case atom.A, atom.Area, atom.Link:
+			return h.Type == html.ElementNode
+		}
+		return false
+	}
+	links := f.FindAll(func(h *html.Node) bool {
+		l := attr.L(h.Attr)
+		return isLink(h) && l.Exists("rel") && l.Exists("href")
+	})
+
+	for l := range links {
+		a := l.Attr()
+		rel, _ := a.Val("rel")
+		href, _ := a.Val("href")
+		u := p.resolve(href)
+
+		rels := unique(strings.Fields(rel))
+		for _, r := range rels {
+			if !slices.Contains(d.Rels[r], u) {
+				d.Rels[r] = append(d.Rels[r], u)
+			}
+		}
+
+		ru, ok := d.RelURLs[u]
+		if !ok {
+			ru = &RelURL{}
+			d.RelURLs[u] = ru
+			ru.Text = textContent(l.Node, false, nil)
+			ru.Title, _ = a.Val("title")
+			ru.Media, _ = a.Val("media")
+			ru.HrefLang, _ = a.Val("hreflang")
+			ru.Type, _ = a.Val("type")
+		}
+		for _, r := range rels {
+			if !slices.Contains(ru.Rels, r) {
+				ru.Rels = append(ru.Rels, r)
+			}
+		}
+		slices.Sort(ru.Rels)
+	}
+}
+
+func (p *parser) resolveURL(s string) *url.URL {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return p.base
+	}
+	if p.base != nil {
+		u = p.base.ResolveReference(u)
+	}
+	return u
+}
+
+func (p *parser) resolve(s string) string {
+	s = strings.TrimSpace(s)
+	if p.base == nil {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return p.base.ResolveReference(u).String()
+}
+
+type propClass struct {
+	prefix, name string
+}
+
+func rootClasses(n *html.Node) (types []string) {
+	cc, _ := attr.L(n.Attr).ClassList()
+	for _, c := range cc {
+		if IsRootClass(c) {
+			types = append(types, c)
+		}
+	}
+	slices.Sort(types)
+	return slices.Compact(types)
+}
+
+func propClasses(n *html.Node) (props []propClass) {
+	cc, _ := attr.L(n.Attr).ClassList()
+	for _, c := range unique(cc) {
+		if m := propRe.FindStringSubmatch(c); m != nil {
+			props = append(props, propClass{m[1], m[2]})
+		}
+	}
+	return props
+}
+
+// elements iterates over the element children of n.
+func elements(n *html.Node) func(func(*html.Node) bool) {
+	return func(yield func(*html.Node) bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+func unique(ss []string) []string {
+	var res []string
+	for _, s := range ss {
+		if !slices.Contains(res, s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func firstString(vv []any) (string, bool) {
+	if len(vv) == 0 {
+		return "", false
+	}
+	s, ok := vv[0].(string)
+	return s, ok
+}
+
+func nonEmpty(s string) bool { return s != "" }
@@ -0,0 +1,114 @@
+package mf2
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+)
+
+func TestSuite(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("..", "testdata", "mf2", "*.html"))
+	if len(files) == 0 {
+		t.Fatal("no testdata")
+	}
+	runCases(t, files)
+}
+
+// TestOfficialSuite runs the parsing tests of the microformats test suite
+// (https://github.com/microformats/tests), a subset of which is kept in
+// testdata; set MF2_SUITE to the tests/microformats-v2 directory
+// of a checkout to run all of them.
+func TestOfficialSuite(t *testing.T) {
+	dir := os.Getenv("MF2_SUITE")
+	if dir == "" {
+		dir = filepath.Join("..", "testdata", "mf2", "microformats-v2")
+	}
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || filepath.Ext(p) != ".html" {
+			return err
+		}
+		if _, err := os.Stat(strings.TrimSuffix(p, ".html") + ".json"); err == nil {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata")
+	}
+	runCases(t, files)
+}
+
+// runCases parses each of the html files, comparing the result
+// to the json file of the same name.
+func runCases(t *testing.T, files []string) {
+	base, _ := url.Parse("http://example.com/")
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.ToSlash(file), ".html")
+		if i := strings.LastIndex(name, "/mf2/"); i >= 0 {
+			name = name[i+len("/mf2/"):]
+		}
+
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			top, _ := htmlx.FinderFromData(f)
+			f.Close()
+
+			exp, err := os.ReadFile(strings.TrimSuffix(file, ".html") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := json.Marshal(Parse(top, base))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expv, resv any
+			json.Unmarshal(exp, &expv)
+			json.Unmarshal(res, &resv)
+			if !reflect.DeepEqual(resv, expv) {
+				t.Errorf("mismatch:\ngot %s\nexp %s", res, exp)
+			}
+		})
+	}
+}
+
+func TestClassNames(t *testing.T) {
+	tab := []struct {
+		class      string
+		root, prop bool
+	}{
+		{"h-entry", true, false},
+		{"h-x-test", true, false},
+		{"h-", false, false},
+		{"h-Entry", false, false},
+		{"p-name", false, true},
+		{"dt-published", false, true},
+		{"e-content", false, true},
+		{"u-in-reply-to", false, true},
+		{"x-name", false, false},
+		{"p-1", false, false},
+	}
+	for i, tc := range tab {
+		if res := IsRootClass(tc.class); res != tc.root {
+			t.Errorf("tc[%d] %s root: got %v, exp %v", i, tc.class, res, tc.root)
+		}
+		if res := IsPropertyClass(tc.class); res != tc.prop {
+			t.Errorf("tc[%d] %s prop: got %v, exp %v", i, tc.class, res, tc.prop)
+		}
+	}
+}
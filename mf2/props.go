@@ -0,0 +1,277 @@
+package mf2
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/attr"
+)
+
+func (p *parser) pValue(n *html.Node) any {
+	if v, ok := p.valueClass(n, false); ok {
+		return v
+	}
+	a := attr.L(n.Attr)
+	switch n.DataAtom {
+	case atom.Abbr, atom.Link:
+		if v, ok := a.Val("title"); ok {
+			return v
+		}
+	case atom.Data, atom.Input:
+		if v, ok := a.Val("value"); ok {
+			return v
+		}
+	case atom.Img, atom.Area:
+		if v, ok := a.Val("alt"); ok {
+			return v
+		}
+	}
+	return textContent(n, true, p)
+}
+
+func (p *parser) uValue(n *html.Node) any {
+	a := attr.L(n.Attr)
+	switch n.DataAtom {
+	case atom.A, atom.Area, atom.Link:
+		if v, ok := a.Val("href"); ok {
+			return p.resolve(v)
+		}
+	case atom.Img:
+		if v, ok := a.Val("src"); ok {
+			if alt, ok := a.Val("alt"); ok {
+				return &Image{Value: p.resolve(v), Alt: alt}
+			}
+			return p.resolve(v)
+		}
+	case atom.Audio, atom.Video, atom.Source, atom.Iframe:
+		if v, ok := a.Val("src"); ok {
+			return p.resolve(v)
+		}
+		if n.DataAtom == atom.Video {
+			if v, ok := a.Val("poster"); ok {
+				return p.resolve(v)
+			}
+		}
+	case atom.Object:
+		if v, ok := a.Val("data"); ok {
+			return p.resolve(v)
+		}
+	}
+	if v, ok := p.valueClass(n, false); ok {
+		return p.resolve(v)
+	}
+	switch n.DataAtom {
+	case atom.Abbr:
+		if v, ok := a.Val("title"); ok {
+			return p.resolve(v)
+		}
+	case atom.Data, atom.Input:
+		if v, ok := a.Val("value"); ok {
+			return p.resolve(v)
+		}
+	}
+	return p.resolve(textContent(n, true, p))
+}
+
+func (p *parser) dtValue(n *html.Node, it *Item) any {
+	v, ok := p.valueClass(n, true)
+	if !ok {
+		a := attr.L(n.Attr)
+		switch n.DataAtom {
+		case atom.Time, atom.Ins, atom.Del:
+			v, ok = a.Val("datetime")
+		case atom.Abbr:
+			v, ok = a.Val("title")
+		case atom.Data, atom.Input:
+			v, ok = a.Val("value")
+		}
+		if !ok {
+			v = textContent(n, false, nil)
+		}
+	}
+	if it == nil {
+		return v
+	}
+	if it.date != "" && timeRe.MatchString(v) {
+		v = it.date + " " + normTime(v)
+	}
+	if it.date == "" {
+		it.date = datePart(v)
+	}
+	return v
+}
+
+func (p *parser) embedded(n *html.Node) *Embedded {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&b, c)
+	}
+	return &Embedded{
+		HTML:  strings.TrimSpace(b.String()),
+		Value: textContent(n, true, p),
+	}
+}
+
+// valueClass implements the value class pattern.
+// For dt-* properties the parts are combined into a date-time.
+func (p *parser) valueClass(n *html.Node, dt bool) (string, bool) {
+	var parts []string
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := range elements(n) {
+			a := attr.L(c.Attr)
+			if len(rootClasses(c)) > 0 || len(propClasses(c)) > 0 {
+				continue
+			}
+			switch {
+			case a.HasClass("value-title"):
+				v, _ := a.Val("title")
+				parts = append(parts, v)
+			case a.HasClass("value"):
+				parts = append(parts, valuePart(c, dt))
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	if len(parts) == 0 {
+		return "", false
+	}
+	if dt {
+		return combineDateTime(parts), true
+	}
+	return strings.Join(parts, ""), true
+}
+
+func valuePart(n *html.Node, dt bool) string {
+	a := attr.L(n.Attr)
+	switch n.DataAtom {
+	case atom.Img, atom.Area:
+		if v, ok := a.Val("alt"); ok {
+			return v
+		}
+	case atom.Data:
+		if v, ok := a.Val("value"); ok {
+			return v
+		}
+	case atom.Abbr:
+		if v, ok := a.Val("title"); ok {
+			return v
+		}
+	case atom.Del, atom.Ins, atom.Time:
+		if v, ok := a.Val("datetime"); dt && ok {
+			return v
+		}
+	}
+	return textContent(n, false, nil)
+}
+
+var (
+	dateRe     = regexp.MustCompile(`^\d{4}-(?:\d{2}-\d{2}|\d{3})$`)
+	timeRe     = regexp.MustCompile(`(?i)^\d{1,2}(?::\d{2}(?::\d{2})?)?\s*(?:[ap]\.?m\.?)?(?:Z|[+-]\d{1,2}:?\d{2})?$`)
+	tzRe       = regexp.MustCompile(`^(?:Z|[+-]\d{1,2}:?\d{2})$`)
+	dateTimeRe = regexp.MustCompile(`^(\d{4}-(?:\d{2}-\d{2}|\d{3}))[T ](.+)$`)
+	ampmRe     = regexp.MustCompile(`(?i)^(\d{1,2})(?::(\d{2})(?::(\d{2}))?)?\s*([ap])\.?m\.?$`)
+)
+
+func combineDateTime(parts []string) string {
+	var date, tim, tz string
+	for _, s := range parts {
+		s = strings.TrimSpace(s)
+		switch {
+		case dateTimeRe.MatchString(s) && date == "" && tim == "":
+			m := dateTimeRe.FindStringSubmatch(s)
+			date, tim = m[1], m[2]
+		case dateRe.MatchString(s) && date == "":
+			date = s
+		case tzRe.MatchString(s) && tz == "":
+			tz = s
+		case timeRe.MatchString(s) && tim == "":
+			tim = normTime(s)
+		}
+	}
+	switch {
+	case date != "" && tim != "":
+		return date + " " + tim + tz
+	case date != "":
+		return date
+	default:
+		return tim + tz
+	}
+}
+
+// normTime converts 12-hour clock times into 24-hour ones.
+func normTime(s string) string {
+	m := ampmRe.FindStringSubmatch(s)
+	if m == nil {
+		return s
+	}
+	h, _ := strconv.Atoi(m[1])
+	h %= 12
+	if strings.EqualFold(m[4], "p") {
+		h += 12
+	}
+	r := fmt.Sprintf("%02d:", h)
+	if m[2] != "" {
+		r += m[2]
+	} else {
+		r += "00"
+	}
+	if m[3] != "" {
+		r += ":" + m[3]
+	}
+	return r
+}
+
+// datePart returns the date part of a dt-* value, if any.
+func datePart(s string) string {
+	if m := dateTimeRe.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	if dateRe.MatchString(s) {
+		return s
+	}
+	return ""
+}
+
+// textContent returns trimmed text of the node, skipping script and style.
+// Images are replaced with their alt text; when withSrc is set, images
+// lacking alt are replaced with their resolved src surrounded by spaces.
+func textContent(n *html.Node, withSrc bool, p *parser) string {
+	var b strings.Builder
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Template:
+				return
+			case atom.Img:
+				a := attr.L(n.Attr)
+				if v, ok := a.Val("alt"); ok {
+					b.WriteString(v)
+				} else if v, ok := a.Val("src"); ok && withSrc {
+					b.WriteString(" " + p.resolve(v) + " ")
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
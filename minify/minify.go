@@ -0,0 +1,258 @@
+// Package minify writes html.Node trees as compact HTML:
+// insignificant whitespace collapsed, optional tags and attribute
+// quotes omitted where the spec allows, comments dropped except
+// the conditional ones, and boolean attributes shortened.
+//
+// The output re-parses to a tree equivalent to the input one.
+package minify
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+type Minifier struct {
+	// KeepComments keeps all comments, not only the conditional ones.
+	KeepComments bool
+
+	// KeepOptionalTags writes all start and end tags.
+	KeepOptionalTags bool
+
+	// KeepQuotes always quotes attribute values.
+	KeepQuotes bool
+}
+
+// Write minifies the subtree using default options.
+func Write(w io.Writer, f htmlx.Finder) error {
+	return Minifier{}.Minify(w, f)
+}
+
+// String minifies the subtree using default options.
+func String(f htmlx.Finder) string {
+	var b strings.Builder
+	Write(&b, f)
+	return b.String()
+}
+
+// Minify writes the subtree rooted at f.
+func (m Minifier) Minify(w io.Writer, f htmlx.Finder) error {
+	if f.IsEmpty() {
+		return nil
+	}
+	mw := &writer{Minifier: m, w: w}
+
+	n := f.Node
+	it := item{n: n}
+	if n.Type == html.TextNode {
+		it.text = collapse(n.Data)
+		if elem.Preserved(n) {
+			it.text = n.Data
+		}
+	}
+	mw.item(it, nil, nil)
+	return mw.err
+}
+
+type writer struct {
+	Minifier
+	w   io.Writer
+	err error
+}
+
+func (w *writer) s(s string) {
+	if w.err == nil {
+		_, w.err = io.WriteString(w.w, s)
+	}
+}
+
+// item is a node to be written; text holds the already
+// whitespace-processed data of text nodes.
+type item struct {
+	n    *html.Node
+	text string
+}
+
+func (w *writer) item(it item, prev, next *item) {
+	n := it.n
+	switch n.Type {
+	case html.DocumentNode:
+		kids := w.kids(n)
+		w.items(kids)
+
+	case html.DoctypeNode:
+		if n.Data == "html" && len(n.Attr) == 0 {
+			w.s("<!doctype html>")
+			return
+		}
+		var b strings.Builder
+		html.Render(&b, n)
+		w.s(b.String())
+
+	case html.CommentNode:
+		w.s("<!--" + n.Data + "-->")
+
+	case html.TextNode:
+		if elem.RawText(n.Parent) {
+			w.s(n.Data)
+			return
+		}
+		w.s(escapeText(it.text))
+
+	case html.ElementNode:
+		w.element(n, prev, next)
+	}
+}
+
+func (w *writer) items(kids []item) {
+	for i := range kids {
+		var prev, next *item
+		if i > 0 {
+			prev = &kids[i-1]
+		}
+		if i+1 < len(kids) {
+			next = &kids[i+1]
+		}
+		w.item(kids[i], prev, next)
+	}
+}
+
+func (w *writer) element(n *html.Node, prev, next *item) {
+	kids := w.kids(n)
+
+	omitStart := !w.KeepOptionalTags && omitStartTag(n, kids, prev)
+	if !omitStart {
+		w.s("<" + tagName(n))
+		for _, a := range n.Attr {
+			w.s(" ")
+			w.attr(n, a)
+		}
+		if n.Namespace != "" && n.FirstChild == nil {
+			w.s("/>")
+			return
+		}
+		w.s(">")
+	}
+	if elem.Void(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Pre, atom.Textarea, atom.Listing:
+		if c := n.FirstChild; c != nil && c.Type == html.TextNode &&
+			strings.HasPrefix(c.Data, "\n") {
+			w.s("\n")
+		}
+	}
+	w.items(kids)
+
+	if w.KeepOptionalTags || !omitEndTag(n, next) {
+		w.s("</" + tagName(n) + ">")
+	}
+}
+
+func (w *writer) attr(n *html.Node, a html.Attribute) {
+	if a.Namespace != "" {
+		w.s(a.Namespace + ":")
+	}
+	w.s(a.Key)
+	if n.Namespace == "" && elem.BooleanAttr(a.Key) &&
+		(a.Val == "" || strings.EqualFold(a.Val, a.Key)) {
+		return
+	}
+	if a.Val == "" {
+		return
+	}
+	v := strings.ReplaceAll(a.Val, "&", "&amp;")
+	switch {
+	case !w.KeepQuotes && !strings.ContainsAny(v, " \t\n\f\r\"'=<>`"):
+		w.s("=" + v)
+	case strings.Contains(v, `"`) && !strings.Contains(v, "'"):
+		w.s("='" + v + "'")
+	default:
+		w.s(`="` + strings.ReplaceAll(v, `"`, "&#34;") + `"`)
+	}
+}
+
+// kids returns the children of n to be written, with comments dropped
+// and whitespace in text collapsed or trimmed where insignificant.
+func (w *writer) kids(n *html.Node) []item {
+	var kids []item
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.CommentNode:
+			if w.KeepComments || elem.Conditional(c) {
+				kids = append(kids, item{n: c})
+			}
+		case html.TextNode:
+			if k := len(kids); k > 0 && kids[k-1].n.Type == html.TextNode {
+				kids[k-1].text += c.Data
+				continue
+			}
+			kids = append(kids, item{n: c, text: c.Data})
+		default:
+			kids = append(kids, item{n: c})
+		}
+	}
+	if elem.Preserved(n) || elem.RawText(n) {
+		return kids
+	}
+
+	res := kids[:0]
+	for i, it := range kids {
+		if it.n.Type != html.TextNode {
+			res = append(res, it)
+			continue
+		}
+		s := collapse(it.text)
+		if elem.Structural(n) && strings.TrimSpace(s) == "" {
+			continue
+		}
+		if i == 0 && !elem.Inline(n) || i > 0 && elem.Block(kids[i-1].n) {
+			s = strings.TrimLeft(s, " ")
+		}
+		if i+1 == len(kids) && !elem.Inline(n) || i+1 < len(kids) && elem.Block(kids[i+1].n) {
+			s = strings.TrimRight(s, " ")
+		}
+		if s == "" {
+			continue
+		}
+		it.text = s
+		res = append(res, it)
+	}
+	return res
+}
+
+func collapse(s string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n', '\f', '\r':
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+		default:
+			b.WriteByte(c)
+			space = false
+		}
+	}
+	return b.String()
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;").Replace(s)
+}
+
+func tagName(n *html.Node) string {
+	if n.Namespace != "" && n.Namespace != "svg" && n.Namespace != "math" {
+		return n.Namespace + ":" + n.Data
+	}
+	return n.Data
+}
@@ -0,0 +1,107 @@
+package minify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/htmltest"
+)
+
+func TestMinify(t *testing.T) {
+	tab := []struct {
+		html, min string
+	}{
+		{"<!DOCTYPE html>\n<html>\n<head>\n  <title>T</title>\n</head>\n<body>\n  <p>a  b</p>\n  <p>c</p>\n</body>\n</html>\n",
+			"<!doctype html><title>T</title><p>a b<p>c"},
+		{`<html lang="en"><body class="x"><div>x</div></body></html>`,
+			`<html lang=en><body class=x><div>x</div>`},
+		{"<ul>\n <li>one</li>\n <li>two <b>bold</b> <i>it</i></li>\n</ul>",
+			"<ul><li>one<li>two <b>bold</b> <i>it</i></ul>"},
+		{"<pre>\n\n  keep\n   this</pre><textarea>\n a  b</textarea>",
+			"<pre>\n\n  keep\n   this</pre><textarea> a  b</textarea>"},
+		{`<!-- drop --><!--[if IE]><p>IE</p><![endif]--><p>x</p>`,
+			`<!--[if IE]><p>IE</p><![endif]--><p>x`},
+		{`<input type="checkbox" checked="checked" disabled value="">`,
+			`<input type=checkbox checked disabled value>`},
+		{`<a title='say "hi"' href="a b" data-x="it's &amp; &quot;q&quot;">x</a>`,
+			`<a data-x="it's &amp; &#34;q&#34;" href="a b" title='say "hi"'>x</a>`},
+		{"<table>\n<thead><tr><th>a</th></tr></thead>\n<tbody><tr><td>1</td><td>2</td></tr>\n<tr><td>3</td></tr></tbody></table>",
+			"<table><thead><tr><th>a<tbody><tr><td>1<td>2<tr><td>3</table>"},
+		{`<div><b><p>x</p></b></div><div><p>y</p>z</div>`,
+			`<div><b><p>x</p></b></div><div><p>y</p>z</div>`},
+		{`<p>1 &lt; 2 &amp;&amp; <script>if (a < b && c) {}</script></p>`,
+			`<p>1 &lt; 2 &amp;&amp; <script>if (a < b && c) {}</script>`},
+		{"<p>a&nbsp; b\u00a0c</p>", "<p>a\u00a0 b\u00a0c"},
+		{`<svg viewBox="0 0 1 1"><path d="M0 0"/><text> a  b </text></svg>`,
+			`<svg viewBox="0 0 1 1"><path d="M0 0"/><text> a  b </text></svg>`},
+	}
+
+	for i, tc := range tab {
+		f, _ := htmlx.FinderFromString(tc.html)
+		res := String(f)
+		if res != tc.min {
+			t.Errorf("tc[%d] mismatch:\ngot %s\nexp %s", i, res, tc.min)
+		}
+		if err := htmltest.Equivalent(tc.html, res); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+	}
+}
+
+func TestKeepOptions(t *testing.T) {
+	f, _ := htmlx.FinderFromString(`<p class="a">x<!-- c --></p><p>y</p>`)
+	m := Minifier{KeepComments: true, KeepOptionalTags: true, KeepQuotes: true}
+
+	var b strings.Builder
+	m.Minify(&b, f)
+	exp := `<html><head></head><body><p class="a">x<!-- c --></p><p>y</p></body></html>`
+	if res := b.String(); res != exp {
+		t.Errorf("mismatch:\ngot %s\nexp %s", res, exp)
+	}
+}
+
+func TestEquivalentFiles(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("..", "testdata", "*.html"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, _ := htmlx.FinderFromString(string(data))
+		res := String(f)
+		if len(res) >= len(data) {
+			t.Errorf("%s: not minified: %d >= %d", file, len(res), len(data))
+		}
+		if err := htmltest.Equivalent(string(data), res); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+		f2, _ := htmlx.FinderFromString(res)
+		if res2 := String(f2); res2 != res {
+			t.Errorf("%s: minifying again changed the output", file)
+		}
+	}
+}
+
+func TestEquivalentHelper(t *testing.T) {
+	tab := []struct {
+		a, b string
+		eq   bool
+	}{
+		{"<p> a  b </p>\n<p>c</p>", "<p>a b<p>c", true},
+		{"<p>a <b>b</b></p>", "<p>a<b>b</b>", false},
+		{"<pre>a  b</pre>", "<pre>a b</pre>", false},
+		{`<input disabled="disabled">`, "<input disabled>", true},
+		{`<b><p>x</p></b>`, `<b><p>x</b>`, false},
+		{`<p><!--[if IE]>x<![endif]--></p>`, `<p>`, false},
+		{"<p>a<br>b", "<p>a <br> b", false},
+		{"<p>a <br>b", "<p>a  <br>b", true},
+	}
+	for i, tc := range tab {
+		if err := htmltest.Equivalent(tc.a, tc.b); (err == nil) != tc.eq {
+			t.Errorf("tc[%d]: got %v, exp equivalent=%v", i, err, tc.eq)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package minify
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// Optional tags, see
+// https://html.spec.whatwg.org/multipage/syntax.html#optional-tags.
+// The rules look at the items actually written, so whitespace and
+// comments already dropped do not count.
+
+func omitStartTag(n *html.Node, kids []item, prev *item) bool {
+	if len(n.Attr) > 0 || n.Namespace != "" {
+		return false
+	}
+	var first *item
+	if len(kids) > 0 {
+		first = &kids[0]
+	}
+
+	switch n.DataAtom {
+	case atom.Html:
+		return first == nil || first.n.Type != html.CommentNode
+
+	case atom.Head:
+		return first == nil || first.n.Type == html.ElementNode
+
+	case atom.Body:
+		if first == nil {
+			return true
+		}
+		switch first.n.Type {
+		case html.CommentNode:
+			return false
+		case html.TextNode:
+			return !startsWithSpace(first.text)
+		}
+		switch first.n.DataAtom {
+		case atom.Meta, atom.Noscript, atom.Link, atom.Script, atom.Style,
+			atom.Template, atom.Base, atom.Basefont, atom.Bgsound,
+			atom.Title, atom.Frameset, atom.Frame, atom.Head, atom.Html:
+			return false
+		}
+		return true
+
+	case atom.Tbody:
+		if first == nil || first.n.DataAtom != atom.Tr {
+			return false
+		}
+		// The rows would join the preceding table section otherwise.
+		if prev != nil && prev.n.Type == html.ElementNode {
+			switch prev.n.DataAtom {
+			case atom.Tbody, atom.Thead, atom.Tfoot:
+				return false
+			}
+		}
+		return true
+
+	case atom.Colgroup:
+		if first == nil || first.n.DataAtom != atom.Col {
+			return false
+		}
+		return prev == nil || prev.n.DataAtom != atom.Colgroup
+	}
+	return false
+}
+
+func omitEndTag(n *html.Node, next *item) bool {
+	if n.Namespace != "" {
+		return false
+	}
+	nextIs := func(aa ...atom.Atom) bool {
+		if next == nil || next.n.Type != html.ElementNode {
+			return false
+		}
+		for _, a := range aa {
+			if next.n.DataAtom == a {
+				return true
+			}
+		}
+		return false
+	}
+	last := next == nil
+	notSpaceOrComment := next == nil ||
+		next.n.Type == html.ElementNode ||
+		next.n.Type == html.TextNode && !startsWithSpace(next.text)
+
+	switch n.DataAtom {
+	case atom.Html, atom.Body:
+		return next == nil || next.n.Type != html.CommentNode
+	case atom.Head:
+		return notSpaceOrComment
+	case atom.Li:
+		return last || nextIs(atom.Li)
+	case atom.Dt:
+		return nextIs(atom.Dt, atom.Dd)
+	case atom.Dd:
+		return last || nextIs(atom.Dd, atom.Dt)
+	case atom.P:
+		if nextIs(atom.Address, atom.Article, atom.Aside, atom.Blockquote,
+			atom.Details, atom.Dialog, atom.Div, atom.Dl, atom.Fieldset,
+			atom.Figcaption, atom.Figure, atom.Footer, atom.Form, atom.H1,
+			atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Header,
+			atom.Hgroup, atom.Hr, atom.Main, atom.Menu, atom.Nav, atom.Ol,
+			atom.P, atom.Pre, atom.Section, atom.Ul) {
+			return true
+		}
+		// The spec excludes a, audio, del, ins, map, noscript, video and
+		// custom element parents; as non-conforming trees can have p inside
+		// formatting elements too, which would trigger the adoption agency,
+		// any inline parent is excluded here.
+		return last && n.Parent != nil && elem.Block(n.Parent)
+	case atom.Rt, atom.Rp:
+		return last || nextIs(atom.Rt, atom.Rp)
+	case atom.Optgroup:
+		return last || nextIs(atom.Optgroup, atom.Hr)
+	case atom.Option:
+		return last || nextIs(atom.Option, atom.Optgroup, atom.Hr)
+	case atom.Colgroup, atom.Caption:
+		return notSpaceOrComment
+	case atom.Thead:
+		return nextIs(atom.Tbody, atom.Tfoot)
+	case atom.Tbody:
+		return last || nextIs(atom.Tbody, atom.Tfoot)
+	case atom.Tfoot:
+		return last
+	case atom.Tr:
+		return last || nextIs(atom.Tr)
+	case atom.Td, atom.Th:
+		return last || nextIs(atom.Td, atom.Th)
+	}
+	return false
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.IndexByte(" \t\n\f\r", s[0]) >= 0
+}
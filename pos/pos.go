@@ -0,0 +1,295 @@
+// Package pos records where in the source the nodes of a parsed
+// html.Node tree come from, in a Map returned along with the tree.
+//
+// html.Parse does not keep positions, so Parse runs html.Tokenizer over
+// the same source alongside it and aligns the tokens with the nodes
+// built from them. Nodes the parser implies, like a missing <body>,
+// get no position; nodes it moves, like foster-parented table content,
+// keep the position of their tags.
+//
+// The alignment cannot follow all the parser does. Not supported are:
+//   - formatting elements the parser clones fixing misnested tags,
+//     like the second <b> of <b>1<p>2</b>, which get no position;
+//   - text merged from separate parts of the source, like foster-parented
+//     text around table rows, which gets the span of its first part.
+package pos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// Position is a point in the source. Line and Col are 1-based,
+// Col counting bytes.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Col    int `json:"col"`
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Span is a source range; End is exclusive.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Pos is the source location of a node. Start is its start tag, or
+// the whole text, comment or doctype. End is the end tag, zero when
+// it was implied or the element is void.
+type Pos struct {
+	Start Span `json:"start"`
+	End   Span `json:"end,omitzero"`
+}
+
+func (p Pos) IsValid() bool {
+	return p.Start.Start.IsValid()
+}
+
+// Map holds the positions of the nodes of a tree.
+type Map map[*html.Node]Pos
+
+// Of returns the recorded position of n, if any.
+func (m Map) Of(n *html.Node) Pos {
+	return m[n]
+}
+
+// Parse parses the HTML document from r, like html.Parse,
+// returning also the positions of the nodes.
+func Parse(r io.Reader) (*html.Node, Map, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := html.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a := &aligner{lines: lineStarts(src), pos: Map{}}
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		a.collect(c)
+	}
+	a.align(src)
+	return doc, a.pos, nil
+}
+
+type aligner struct {
+	lines []int
+	nodes []*html.Node // in pre-order
+	ptr   int          // nodes before it are behind the tokens seen
+	open  []*html.Node
+	last  int // index of the last located text node, or -1
+	pos   Map
+}
+
+func (a *aligner) collect(n *html.Node) {
+	a.nodes = append(a.nodes, n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		a.collect(c)
+	}
+}
+
+func (a *aligner) align(src []byte) {
+	z := html.NewTokenizer(bytes.NewReader(src))
+	off := 0
+	a.last = -1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		raw := len(z.Raw())
+		sp := a.span(off, off+raw)
+		off += raw
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			i := a.find(func(n *html.Node) bool {
+				return n.Type == html.ElementNode && isTag(n, string(name))
+			})
+			if i < 0 {
+				continue
+			}
+			n := a.nodes[i]
+			a.pos[n] = Pos{Start: sp}
+			if tt == html.StartTagToken && !elem.Void(n) ||
+				tt == html.SelfClosingTagToken && n.Namespace == "" && !elem.Void(n) {
+				a.open = append(a.open, n)
+			}
+			a.last = -1
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if a.close(string(name), sp) {
+				a.last = -1
+			}
+
+		case html.TextToken:
+			s := string(z.Text())
+			a.text(s, sp)
+
+		case html.CommentToken:
+			s := string(z.Text())
+			if i := a.find(func(n *html.Node) bool {
+				return n.Type == html.CommentNode && n.Data == s
+			}); i >= 0 {
+				a.pos[a.nodes[i]] = Pos{Start: sp}
+			}
+			a.last = -1
+
+		case html.DoctypeToken:
+			if i := a.find(func(n *html.Node) bool {
+				return n.Type == html.DoctypeNode
+			}); i >= 0 {
+				a.pos[a.nodes[i]] = Pos{Start: sp}
+			}
+			a.last = -1
+		}
+	}
+}
+
+// find returns the index of the first unlocated node satisfying ok,
+// searching forward from ptr and then, for nodes the parser moved,
+// from the beginning.
+func (a *aligner) find(ok func(*html.Node) bool) int {
+	for i := a.ptr; i < len(a.nodes); i++ {
+		if _, done := a.pos[a.nodes[i]]; !done && ok(a.nodes[i]) {
+			a.ptr = i + 1
+			return i
+		}
+	}
+	return a.findBefore(ok)
+}
+
+// fostering tells if text goes before the table being parsed,
+// as there is no cell open for it.
+func (a *aligner) fostering() bool {
+	if len(a.open) == 0 {
+		return false
+	}
+	switch a.open[len(a.open)-1].DataAtom {
+	case atom.Table, atom.Tbody, atom.Thead, atom.Tfoot, atom.Tr:
+		return true
+	}
+	return false
+}
+
+// findBefore is like find, searching only the nodes behind ptr.
+func (a *aligner) findBefore(ok func(*html.Node) bool) int {
+	for i := 0; i < a.ptr; i++ {
+		if _, done := a.pos[a.nodes[i]]; !done && ok(a.nodes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *aligner) close(name string, sp Span) bool {
+	for j := len(a.open) - 1; j >= 0; j-- {
+		if n := a.open[j]; isTag(n, name) {
+			p := a.pos[n]
+			p.End = sp
+			a.pos[n] = p
+			a.open = a.open[:j]
+			return true
+		}
+	}
+	// A stray </p> or </br> makes the parser create an element.
+	if a.ptr < len(a.nodes) {
+		n := a.nodes[a.ptr]
+		if _, done := a.pos[n]; !done && n.Type == html.ElementNode &&
+			isTag(n, name) && n.FirstChild == nil {
+			a.pos[n] = Pos{Start: sp, End: sp}
+			a.ptr++
+			return true
+		}
+	}
+	return false
+}
+
+// isTag tells if the element n has the tag name, as the tokenizer gives
+// it in lower case; the parser restores the case of SVG names, like
+// foreignObject.
+func isTag(n *html.Node, name string) bool {
+	if n.Namespace == "svg" {
+		return strings.EqualFold(n.Data, name)
+	}
+	return n.Data == name
+}
+
+// text locates the text node holding s, extending the span of the last
+// one if s is its continuation, like after an ignored tag.
+func (a *aligner) text(s string, sp Span) {
+	t := strings.TrimSpace(s)
+	if a.last >= 0 && strings.Contains(a.nodes[a.last].Data, s) {
+		n := a.nodes[a.last]
+		p := a.pos[n]
+		p.Start.End = sp.End
+		a.pos[n] = p
+		return
+	}
+	var i int
+	if t == "" {
+		// Whitespace is often dropped, so it is looked up in place only.
+		i = -1
+		if a.ptr < len(a.nodes) {
+			n := a.nodes[a.ptr]
+			if _, done := a.pos[n]; !done && n.Type == html.TextNode &&
+				strings.HasPrefix(n.Data, s) {
+				i = a.ptr
+				a.ptr++
+			}
+		}
+	} else {
+		ok := func(n *html.Node) bool {
+			return n.Type == html.TextNode && strings.Contains(n.Data, t)
+		}
+		i = -1
+		if a.fostering() {
+			i = a.findBefore(ok)
+		}
+		if i < 0 {
+			i = a.find(ok)
+		}
+	}
+	if i >= 0 {
+		a.pos[a.nodes[i]] = Pos{Start: sp}
+	}
+	a.last = i
+}
+
+func (a *aligner) span(start, end int) Span {
+	return Span{a.position(start), a.position(end)}
+}
+
+func (a *aligner) position(off int) Position {
+	i := sort.SearchInts(a.lines, off+1) - 1
+	return Position{Offset: off, Line: i + 1, Col: off - a.lines[i] + 1}
+}
+
+func lineStarts(src []byte) []int {
+	ll := []int{0}
+	for i, c := range src {
+		if c == '\n' {
+			ll = append(ll, i+1)
+		}
+	}
+	return ll
+}
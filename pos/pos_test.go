@@ -0,0 +1,219 @@
+package pos
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParse(t *testing.T) {
+	src := "<!DOCTYPE html>\n<title>T</title>\n<ul>\n  <li>one\n  <li class=x>two</li>\n</ul>\n<!-- c --><br></p>"
+	doc, ps, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tab := []struct {
+		path       string
+		start, end string
+	}{
+		{"doctype", "1:1", ""},
+		{"title", "2:1", "2:9"},
+		{"title/T", "2:8", ""},
+		{"ul", "3:1", "6:1"},
+		{"ul/li", "4:3", ""},
+		{"ul/li/one", "4:7", ""},
+		{"ul/li[2]", "5:3", "5:18"},
+		{"comment", "7:1", ""},
+		{"br", "7:11", ""},
+		{"p", "7:15", "7:15"},
+		{"html", "", ""},
+		{"body", "", ""},
+	}
+	nodes := index(doc)
+	for i, tc := range tab {
+		n := nodes[tc.path]
+		if n == nil {
+			t.Errorf("tc[%d]: no node %s", i, tc.path)
+			continue
+		}
+		p := ps.Of(n)
+		start, end := "", ""
+		if p.Start.Start.IsValid() {
+			start = p.Start.Start.String()
+		}
+		if p.End.Start.IsValid() {
+			end = p.End.Start.String()
+		}
+		if start != tc.start || end != tc.end {
+			t.Errorf("tc[%d] %s mismatch:\ngot %q %q\nexp %q %q",
+				i, tc.path, start, end, tc.start, tc.end)
+		}
+	}
+
+	li := ps.Of(nodes["ul/li[2]"])
+	if s := src[li.Start.Start.Offset:li.End.End.Offset]; s != "<li class=x>two</li>" {
+		t.Errorf("li source: got %q", s)
+	}
+}
+
+// TestParseMoved checks the nodes the parser moves, merges or clones,
+// including the cases documented as not supported.
+func TestParseMoved(t *testing.T) {
+	tab := []struct {
+		src   string
+		path  string
+		start string
+	}{
+		// Foster-parented text goes before the table.
+		{"<table>x<tr><td>y</table>", "x", "1:8"},
+		{"<table>y<tr><td>y</table>", "y", "1:8"},
+		{"<table>y<tr><td>y</table>", "table/tbody/tr/td/y", "1:17"},
+		{"<table><tr><td>1</td></tr>z</table>", "z", "1:27"},
+		// Merged text gets the span of its first part.
+		{"<table>a<tr><td>b</td></tr>c</table>", "ac", "1:8"},
+		// Cloned formatting elements get no position, their content does.
+		{"<p><b>1<p>2</b>3", "p[2]/b", ""},
+		{"<p><b>1<p>2</b>3", "p[2]/b/2", "1:11"},
+		{"<b><i>x</b>y</i>", "i", ""},
+		{"<b><i>x</b>y</i>", "i/y", "1:12"},
+	}
+	for i, tc := range tab {
+		doc, ps, err := Parse(strings.NewReader(tc.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := index(doc)[tc.path]
+		if n == nil {
+			t.Errorf("tc[%d]: no node %s", i, tc.path)
+			continue
+		}
+		start := ""
+		if p := ps.Of(n); p.IsValid() {
+			start = p.Start.Start.String()
+		}
+		if start != tc.start {
+			t.Errorf("tc[%d] %s %s mismatch:\ngot %q\nexp %q", i, tc.src, tc.path, start, tc.start)
+		}
+	}
+}
+
+// TestParseForeign checks the SVG elements, whose names the parser
+// gives in camel case.
+func TestParseForeign(t *testing.T) {
+	src := `<svg><clipPath id=c><rect/></clipPath><foreignObject><p>x</p></foreignObject><text>t</text></svg>`
+	doc, ps, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab := []struct {
+		path       string
+		start, end string
+	}{
+		{"svg/clipPath", "1:6", "1:28"},
+		{"svg/clipPath/rect", "1:21", ""},
+		{"svg/foreignObject", "1:39", "1:62"},
+		{"svg/foreignObject/p", "1:54", "1:58"},
+		{"svg/foreignObject/p/x", "1:57", ""},
+		{"svg/text", "1:78", "1:85"},
+		{"svg/text/t", "1:84", ""},
+	}
+	nodes := index(doc)
+	for i, tc := range tab {
+		n := nodes[tc.path]
+		if n == nil {
+			t.Errorf("tc[%d]: no node %s", i, tc.path)
+			continue
+		}
+		p := ps.Of(n)
+		start, end := "", ""
+		if p.Start.Start.IsValid() {
+			start = p.Start.Start.String()
+		}
+		if p.End.Start.IsValid() {
+			end = p.End.Start.String()
+		}
+		if start != tc.start || end != tc.end {
+			t.Errorf("tc[%d] %s mismatch:\ngot %q %q\nexp %q %q",
+				i, tc.path, start, end, tc.start, tc.end)
+		}
+	}
+}
+
+func TestParseGov(t *testing.T) {
+	src, err := os.ReadFile("../testdata/gatesofvienna.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, ps, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total, located int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			total++
+			if p := ps.Of(n); p.IsValid() {
+				located++
+				tag := string(src[p.Start.Start.Offset:p.Start.End.Offset])
+				if !strings.HasPrefix(strings.ToLower(tag), "<"+n.Data) {
+					t.Errorf("<%s> located at %v: %q", n.Data, p.Start.Start, tag)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	// Only the implied elements may lack positions.
+	if total-located > 5 {
+		t.Errorf("located %d of %d elements", located, total)
+	}
+}
+
+// index maps simple paths, like ul/li[2], to the nodes,
+// with text nodes named by their trimmed text.
+func index(doc *html.Node) map[string]*html.Node {
+	m := map[string]*html.Node{}
+	var walk func(n *html.Node, prefix string)
+	walk = func(n *html.Node, prefix string) {
+		seen := map[string]int{}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			var name string
+			switch c.Type {
+			case html.ElementNode:
+				name = c.Data
+			case html.TextNode:
+				name = strings.TrimSpace(c.Data)
+			case html.CommentNode:
+				name = "comment"
+			case html.DoctypeNode:
+				name = "doctype"
+			}
+			if name == "" {
+				continue
+			}
+			seen[name]++
+			if seen[name] > 1 {
+				name += "[" + strconv.Itoa(seen[name]) + "]"
+			}
+			path := prefix + name
+			m[path] = c
+			if c.Data == "html" || c.Data == "head" || c.Data == "body" {
+				walk(c, "")
+				continue
+			}
+			walk(c, path+"/")
+		}
+	}
+	walk(doc, "")
+	return m
+}
@@ -0,0 +1,139 @@
+package pp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// graph walks the tree for the DOT and Mermaid formats,
+// numbering the nodes drawn.
+type graph struct {
+	Printer
+	w   io.Writer
+	err error
+	ids int
+}
+
+type graphSyntax interface {
+	header() string
+	node(id int, label string, n *html.Node, hl bool) string
+	edge(from, to int) string
+	footer(hl []int) string
+}
+
+func (p Printer) printGraph(w io.Writer, top *html.Node, syn graphSyntax) error {
+	p.Color, p.Width = false, 0
+	g := &graph{Printer: p, w: w}
+	var hl []int
+
+	var f func(*html.Node, int) int
+	f = func(node *html.Node, depth int) int {
+		id := g.ids
+		g.ids++
+		match := p.Highlight != nil && p.Highlight(node)
+		if match {
+			hl = append(hl, id)
+		}
+		g.s(syn.node(id, p.label(node), node, match))
+
+		for _, e := range p.entries(node, depth) {
+			if e.node != nil {
+				g.s(syn.edge(id, f(e.node, depth+1)))
+				continue
+			}
+			more := g.ids
+			g.ids++
+			g.s(syn.node(more, e.summary(), nil, false))
+			g.s(syn.edge(id, more))
+		}
+		return id
+	}
+
+	g.s(syn.header())
+	f(top, 0)
+	g.s(syn.footer(hl))
+	return g.err
+}
+
+func (g *graph) s(s string) {
+	if g.err == nil && s != "" {
+		_, g.err = io.WriteString(g.w, s)
+	}
+}
+
+type dot struct{}
+
+func (dot) header() string {
+	return "digraph html {\n\tnode [fontname=monospace fontsize=10];\n"
+}
+
+func (dot) node(id int, label string, n *html.Node, hl bool) string {
+	shape, styles := "box", []string{}
+	switch {
+	case n == nil || n.Type == html.TextNode:
+		shape = "plaintext"
+	case n.Type == html.CommentNode:
+		shape = "note"
+	case n.Type != html.ElementNode:
+		styles = append(styles, "rounded")
+	}
+	attrs := "shape=" + shape
+	if hl {
+		styles = append(styles, "filled")
+		attrs += ", fillcolor=yellow"
+	}
+	if len(styles) > 0 {
+		attrs += `, style="` + strings.Join(styles, ",") + `"`
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return fmt.Sprintf("\tn%d [%s, label=\"%s\"];\n", id, attrs, r.Replace(label))
+}
+
+func (dot) edge(from, to int) string {
+	return fmt.Sprintf("\tn%d -> n%d;\n", from, to)
+}
+
+func (dot) footer([]int) string {
+	return "}\n"
+}
+
+type mermaid struct{}
+
+func (mermaid) header() string {
+	return "flowchart TD\n"
+}
+
+func (mermaid) node(id int, label string, n *html.Node, _ bool) string {
+	r := strings.NewReplacer(
+		"#", "#35;", `"`, "#quot;", "`", "#96;", "<", "#lt;", ">", "#gt;",
+		"\n", " ", "\r", " ",
+	)
+	open, close := "[", "]"
+	if n != nil && n.Type != html.ElementNode {
+		open, close = "(", ")"
+	}
+	return fmt.Sprintf("\tn%d%s\"%s\"%s\n", id, open, r.Replace(label), close)
+}
+
+func (mermaid) edge(from, to int) string {
+	return fmt.Sprintf("\tn%d --> n%d\n", from, to)
+}
+
+func (mermaid) footer(hl []int) string {
+	if len(hl) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\tclassDef match fill:#ff0,stroke:#333\n\tclass ")
+	for i, id := range hl {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "n%d", id)
+	}
+	b.WriteString(" match\n")
+	return b.String()
+}
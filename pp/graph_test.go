@@ -0,0 +1,61 @@
+package pp
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/pred"
+)
+
+func TestGraph(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader("<p class=\"a\">x\"y <b>z</b></p>\n<!--c-->"))
+	body := doc.FirstChild.LastChild
+
+	tab := []struct {
+		p   Printer
+		exp string
+	}{
+		{Printer{Format: DOT, TrimEmptyAttr: true, Highlight: pred.Element(atom.B)},
+			`digraph html {
+	node [fontname=monospace fontsize=10];
+	n0 [shape=box, label="T:ELEM D:` + "`body`" + `"];
+	n1 [shape=box, label="T:ELEM D:` + "`p`" + ` A:[class=\"a\"]"];
+	n2 [shape=plaintext, label="T:TEXT D:` + "`x\\\"y `" + `"];
+	n1 -> n2;
+	n3 [shape=box, fillcolor=yellow, style="filled", label="T:ELEM D:` + "`b`" + `"];
+	n4 [shape=plaintext, label="T:TEXT D:` + "`z`" + `"];
+	n3 -> n4;
+	n1 -> n3;
+	n0 -> n1;
+	n5 [shape=plaintext, label="T:TEXT D:` + "`\\\\n`" + `"];
+	n0 -> n5;
+	n6 [shape=note, label="T:COMMENT D:` + "`c`" + `"];
+	n0 -> n6;
+}
+`},
+		{Printer{Format: Mermaid, TrimEmptyAttr: true, SkipSpaces: true, MaxDepth: 1,
+			Highlight: pred.Element(atom.P)},
+			`flowchart TD
+	n0["T:ELEM D:#96;body#96;"]
+	n1["T:ELEM D:#96;p#96; A:[class=#quot;a#quot;]"]
+	n2["... ×2 more"]
+	n1 --> n2
+	n0 --> n1
+	n3("T:COMMENT D:#96;c#96;")
+	n0 --> n3
+	classDef match fill:#ff0,stroke:#333
+	class n1 match
+`},
+	}
+
+	for i, tc := range tab {
+		var b strings.Builder
+		tc.p.Print(&b, body)
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
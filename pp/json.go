@@ -0,0 +1,228 @@
+package pp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx/pos"
+)
+
+// jsonNode is the JSON form of an html.Node. In the JSON format
+// the nodes nest in children; in NDJSON each node is a separate line,
+// in document order, linked to its parent by id.
+type jsonNode struct {
+	ID        *int        `json:"id,omitempty"`
+	Parent    *int        `json:"parent,omitempty"`
+	Type      string      `json:"type"`
+	Data      string      `json:"data,omitempty"`
+	Namespace string      `json:"namespace,omitempty"`
+	Attrs     []jsonAttr  `json:"attrs,omitempty"`
+	Pos       *pos.Pos    `json:"pos,omitempty"`
+	Path      string      `json:"path,omitempty"`
+	Children  []*jsonNode `json:"children,omitempty"`
+}
+
+type jsonAttr struct {
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+	Val       string `json:"val"`
+}
+
+var jsonTypes = map[html.NodeType]string{
+	html.ErrorNode:    "error",
+	html.DocumentNode: "document",
+	html.DoctypeNode:  "doctype",
+	html.ElementNode:  "element",
+	html.TextNode:     "text",
+	html.CommentNode:  "comment",
+	html.RawNode:      "raw",
+}
+
+func (p Printer) printJSON(w io.Writer, top *html.Node) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p.jsonTree(top, ""))
+}
+
+func (p Printer) printNDJSON(w io.Writer, top *html.Node) error {
+	enc := json.NewEncoder(w)
+	id := 0
+
+	var f func(*html.Node, *int, string) error
+	f = func(node *html.Node, parent *int, path string) error {
+		jn := p.jsonNode(node, path)
+		jn.ID, jn.Parent = new(int), parent
+		*jn.ID = id
+		id++
+		if err := enc.Encode(jn); err != nil {
+			return err
+		}
+		steps := childSteps(node)
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if err := f(c, jn.ID, path+steps[c]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return f(top, nil, "")
+}
+
+func (p Printer) jsonTree(node *html.Node, path string) *jsonNode {
+	jn := p.jsonNode(node, path)
+	steps := childSteps(node)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		jn.Children = append(jn.Children, p.jsonTree(c, path+steps[c]))
+	}
+	return jn
+}
+
+func (p Printer) jsonNode(node *html.Node, path string) *jsonNode {
+	jn := &jsonNode{
+		Type:      jsonTypes[node.Type],
+		Data:      node.Data,
+		Namespace: node.Namespace,
+	}
+	for _, a := range node.Attr {
+		jn.Attrs = append(jn.Attrs, jsonAttr{a.Namespace, a.Key, a.Val})
+	}
+	if p.Positions != nil {
+		if np := p.Positions.Of(node); np.IsValid() {
+			jn.Pos = &np
+		}
+	}
+	if p.Paths {
+		jn.Path = path
+		if path == "" {
+			jn.Path = "/"
+		}
+	}
+	return jn
+}
+
+// Path returns the XPath-like path of n from the root of its tree,
+// as written in the JSON formats.
+func Path(n *html.Node) string {
+	var steps []string
+	for ; n.Parent != nil; n = n.Parent {
+		steps = append(steps, childSteps(n.Parent)[n])
+	}
+	if len(steps) == 0 {
+		return "/"
+	}
+	slices.Reverse(steps)
+	return strings.Join(steps, "")
+}
+
+// childSteps returns XPath-like steps to the children of n,
+// like /div[2] or /text()[1].
+func childSteps(n *html.Node) map[*html.Node]string {
+	name := func(c *html.Node) string {
+		switch c.Type {
+		case html.ElementNode:
+			return c.Data
+		case html.TextNode:
+			return "text()"
+		case html.CommentNode:
+			return "comment()"
+		}
+		return "node()"
+	}
+	count := map[string]int{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		count[name(c)]++
+	}
+	seen := map[string]int{}
+	steps := map[*html.Node]string{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s := name(c)
+		if count[s] > 1 {
+			seen[s]++
+			s += "[" + strconv.Itoa(seen[s]) + "]"
+		}
+		steps[c] = "/" + s
+	}
+	return steps
+}
+
+// Decode reads a tree written in the JSON or NDJSON format,
+// returning also the source positions, if present.
+func Decode(r io.Reader) (*html.Node, pos.Map, error) {
+	dec := json.NewDecoder(r)
+	var root *html.Node
+	byID := map[int]*html.Node{}
+	positions := pos.Map{}
+
+	for {
+		var jn jsonNode
+		err := dec.Decode(&jn)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		n, err := jn.node(positions)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if jn.ID == nil || jn.Parent == nil {
+			if root != nil {
+				return nil, nil, errors.New("more than one root node")
+			}
+			root = n
+		} else {
+			parent := byID[*jn.Parent]
+			if parent == nil {
+				return nil, nil, fmt.Errorf("node %d: unknown parent %d", *jn.ID, *jn.Parent)
+			}
+			parent.AppendChild(n)
+		}
+		if jn.ID != nil {
+			byID[*jn.ID] = n
+		}
+	}
+	if root == nil {
+		return nil, nil, errors.New("no nodes")
+	}
+	return root, positions, nil
+}
+
+func (jn *jsonNode) node(positions pos.Map) (*html.Node, error) {
+	n := &html.Node{Data: jn.Data, Namespace: jn.Namespace}
+	found := false
+	for t, s := range jsonTypes {
+		if s == jn.Type {
+			n.Type, found = t, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown node type: %q", jn.Type)
+	}
+	if n.Type == html.ElementNode {
+		n.DataAtom = atom.Lookup([]byte(n.Data))
+	}
+	for _, a := range jn.Attrs {
+		n.Attr = append(n.Attr, html.Attribute{Namespace: a.Namespace, Key: a.Key, Val: a.Val})
+	}
+	if jn.Pos != nil {
+		positions[n] = *jn.Pos
+	}
+	for _, c := range jn.Children {
+		cn, err := c.node(positions)
+		if err != nil {
+			return nil, err
+		}
+		n.AppendChild(cn)
+	}
+	return n, nil
+}
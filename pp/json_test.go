@@ -0,0 +1,98 @@
+package pp
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/pos"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, file := range []string{"simple.html", "gatesofvienna.html", "article.html"} {
+		src, err := os.ReadFile("../testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, ps, err := pos.Parse(bytes.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var exp strings.Builder
+		html.Render(&exp, doc)
+
+		for _, format := range []Format{JSON, NDJSON} {
+			var b bytes.Buffer
+			Printer{Format: format, Positions: ps, Paths: true}.Print(&b, doc)
+
+			doc2, ps2, err := Decode(&b)
+			if err != nil {
+				t.Errorf("%s format %d: %v", file, format, err)
+				continue
+			}
+			var res strings.Builder
+			html.Render(&res, doc2)
+			if res.String() != exp.String() {
+				t.Errorf("%s format %d: trees differ", file, format)
+			}
+
+			if !samePositions(ps, doc, ps2, doc2) {
+				t.Errorf("%s format %d: positions not restored", file, format)
+			}
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<p>a<b>b</b>c</p><p><!--x--></p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc.LastChild.LastChild
+	tab := []struct {
+		node *html.Node
+		exp  string
+	}{
+		{doc, "/"},
+		{body, "/html/body"},
+		{body.FirstChild, "/html/body/p[1]"},
+		{body.FirstChild.LastChild, "/html/body/p[1]/text()[2]"},
+		{body.FirstChild.FirstChild.NextSibling, "/html/body/p[1]/b"},
+		{body.LastChild.FirstChild, "/html/body/p[2]/comment()"},
+	}
+	for i, tc := range tab {
+		if res := Path(tc.node); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tab := []string{
+		``,
+		`{"type":"bogus"}`,
+		`{"type":"document"}{"type":"document"}`,
+		`{"id":0,"type":"document"}` + "\n" + `{"id":1,"parent":7,"type":"text"}`,
+		`{"type":`,
+	}
+	for i, tc := range tab {
+		if _, _, err := Decode(strings.NewReader(tc)); err == nil {
+			t.Errorf("tc[%d]: expected error", i)
+		}
+	}
+}
+
+func samePositions(pa pos.Map, a *html.Node, pb pos.Map, b *html.Node) bool {
+	if pa.Of(a) != pb.Of(b) {
+		return false
+	}
+	for a, b = a.FirstChild, b.FirstChild; a != nil && b != nil; a, b = a.NextSibling, b.NextSibling {
+		if !samePositions(pa, a, pb, b) {
+			return false
+		}
+	}
+	return a == nil && b == nil
+}
@@ -0,0 +1,113 @@
+package pp
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// entry is a child to be printed: a node, or a summary
+// of more nodes left out.
+type entry struct {
+	node    *html.Node
+	more    int
+	similar bool
+}
+
+func (e entry) summary() string {
+	s := "... ×" + strconv.Itoa(e.more) + " more"
+	if e.similar {
+		s += " similar"
+	}
+	return s
+}
+
+// entries lists the children of node at the given depth,
+// applying MaxDepth, MaxChildren, CollapseSimilar and SkipSpaces.
+func (p Printer) entries(node *html.Node, depth int) []entry {
+	if p.MaxDepth > 0 && depth >= p.MaxDepth {
+		if k := p.count(node.FirstChild); k > 0 {
+			return []entry{{more: k}}
+		}
+		return nil
+	}
+
+	var ee []entry
+	shown := 0
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if p.skip(c) {
+			continue
+		}
+		if p.MaxChildren > 0 && shown == p.MaxChildren {
+			ee = append(ee, entry{more: p.count(c)})
+			break
+		}
+		ee = append(ee, entry{node: c})
+		shown++
+
+		if !p.CollapseSimilar || c.Type != html.ElementNode {
+			continue
+		}
+		sh := shape(c)
+		n, last := 0, c
+		for d := c.NextSibling; d != nil; d = d.NextSibling {
+			if d.Type == html.TextNode && strings.TrimSpace(d.Data) == "" {
+				continue
+			}
+			if d.Type != html.ElementNode || shape(d) != sh {
+				break
+			}
+			n, last = n+1, d
+		}
+		if n > 0 {
+			ee = append(ee, entry{more: n, similar: true})
+			c = last
+		}
+	}
+	return ee
+}
+
+// count counts the nodes from n on, not skipped.
+func (p Printer) count(n *html.Node) (k int) {
+	for ; n != nil; n = n.NextSibling {
+		if !p.skip(n) {
+			k++
+		}
+	}
+	return k
+}
+
+func (p Printer) skip(n *html.Node) bool {
+	return p.SkipSpaces && n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+}
+
+// shape describes the element structure of the subtree: names
+// and classes of the elements, ignoring text and other attributes.
+func shape(n *html.Node) string {
+	var b strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		b.WriteString(n.Data)
+		for _, a := range n.Attr {
+			if a.Key == "class" && a.Namespace == "" {
+				cc := strings.Fields(a.Val)
+				slices.Sort(cc)
+				for _, c := range cc {
+					b.WriteString("." + c)
+				}
+			}
+		}
+		b.WriteByte('(')
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				f(c)
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte(')')
+	}
+	f(n)
+	return b.String()
+}
@@ -0,0 +1,272 @@
+package pp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Parse reads a tree printed in the text format, with or without
+// CompactSpaces and TrimEmptyAttr, back into html.Node. The output of
+// Printer is lossless, unless it was colored, truncated or limited.
+// L: positions are skipped.
+func Parse(r io.Reader) (*html.Node, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+
+	var root *html.Node
+	var stack []*html.Node // the last node at each depth
+	lineno := 0
+
+	for sc.Scan() {
+		lineno++
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rest := strings.TrimLeft(line, " ")
+		indent := len(line) - len(rest)
+		if indent%2 != 0 {
+			return nil, fmt.Errorf("line %d: odd indentation", lineno)
+		}
+		depth := indent / 2
+
+		n, err := parseNode(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+
+		switch {
+		case depth == 0:
+			if root != nil {
+				return nil, fmt.Errorf("line %d: more than one root node", lineno)
+			}
+			root = n
+		case depth > len(stack):
+			return nil, fmt.Errorf("line %d: indented too deep", lineno)
+		default:
+			stack[depth-1].AppendChild(n)
+		}
+		stack = append(stack[:depth], n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, errors.New("no nodes")
+	}
+	return root, nil
+}
+
+func parseNode(s string) (*html.Node, error) {
+	p := &lineParser{s: s}
+	n := &html.Node{}
+
+	if !p.prefix("T:") {
+		return nil, errors.New("T: expected")
+	}
+	typ := p.until(" ")
+	found := false
+	for t, name := range nodeTypes {
+		if name == typ {
+			n.Type, found = t, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown node type %q", typ)
+	}
+
+	if !p.prefix(" D:") {
+		return nil, errors.New("D: expected")
+	}
+	var err error
+	if p.prefix("`") {
+		n.Data, err = p.quoted('`')
+	} else {
+		n.Data, err = spaces(p.until(" "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.prefix(" N:") {
+		if n.Namespace, err = p.key(); err != nil {
+			return nil, err
+		}
+	}
+	if n.Type == html.ElementNode {
+		n.DataAtom = atom.Lookup([]byte(n.Data))
+	}
+
+	if p.prefix(" A:") && p.prefix("[") {
+		for {
+			a, err := p.attr()
+			if err != nil {
+				return nil, err
+			}
+			n.Attr = append(n.Attr, a)
+			if p.prefix("]") {
+				break
+			}
+			if !p.prefix(" ") {
+				return nil, errors.New("attribute separator expected")
+			}
+		}
+	}
+
+	if p.prefix(" L:") {
+		p.until(" ")
+	}
+	if p.s != "" {
+		return nil, fmt.Errorf("unexpected %q", p.s)
+	}
+	return n, nil
+}
+
+type lineParser struct {
+	s string
+}
+
+func (p *lineParser) prefix(pre string) bool {
+	if strings.HasPrefix(p.s, pre) {
+		p.s = p.s[len(pre):]
+		return true
+	}
+	return false
+}
+
+func (p *lineParser) until(stop string) string {
+	i := strings.IndexAny(p.s, stop)
+	if i < 0 {
+		i = len(p.s)
+	}
+	t := p.s[:i]
+	p.s = p.s[i:]
+	return t
+}
+
+// quoted reads the rest of an escaped string up to the closing quote.
+func (p *lineParser) quoted(quote byte) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(p.s); i++ {
+		switch c := p.s[i]; c {
+		case quote:
+			p.s = p.s[i+1:]
+			return b.String(), nil
+		case '\\':
+			n, err := unescape(&b, p.s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated %c", quote)
+}
+
+// key reads an escaped attribute key or namespace.
+func (p *lineParser) key() (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(p.s); i++ {
+		switch c := p.s[i]; c {
+		case ':', '=', ' ', ']':
+			p.s = p.s[i:]
+			return b.String(), nil
+		case '\\':
+			n, err := unescape(&b, p.s[i+1:])
+			if err != nil {
+				return "", err
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.s = ""
+	return b.String(), nil
+}
+
+func (p *lineParser) attr() (a html.Attribute, err error) {
+	if a.Key, err = p.key(); err != nil {
+		return a, err
+	}
+	if p.prefix(":") {
+		a.Namespace = a.Key
+		if a.Key, err = p.key(); err != nil {
+			return a, err
+		}
+	}
+	if p.prefix(`="`) {
+		a.Val, err = p.quoted('"')
+	}
+	return a, err
+}
+
+// unescape writes the character escaped at the start of s,
+// returning the length of the escape.
+func unescape(b *strings.Builder, s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("dangling backslash")
+	}
+	switch s[0] {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'x':
+		if len(s) < 3 {
+			return 0, errors.New("short \\x escape")
+		}
+		c, err := strconv.ParseUint(s[1:3], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("bad \\x escape: %w", err)
+		}
+		b.WriteByte(byte(c))
+		return 3, nil
+	default:
+		b.WriteByte(s[0])
+	}
+	return 1, nil
+}
+
+var spaceTokens = map[string]string{
+	"LF": "\n", "CR": "\r", "TAB": "\t", "SPC": " ", "FF": "\f",
+}
+
+// spaces reverses ppSpaces.
+func spaces(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	var b strings.Builder
+	for _, tok := range strings.Split(s, ",") {
+		cnt := 1
+		if i := strings.LastIndexByte(tok, 'x'); i > 0 {
+			var err error
+			if cnt, err = strconv.Atoi(tok[i+1:]); err != nil {
+				return "", fmt.Errorf("bad space count in %q", tok)
+			}
+			tok = tok[:i]
+		}
+		sp, ok := spaceTokens[tok]
+		if !ok {
+			r, err := strconv.ParseUint(strings.TrimPrefix(tok, "U+"), 16, 32)
+			if err != nil || !strings.HasPrefix(tok, "U+") {
+				return "", fmt.Errorf("unknown space token %q", tok)
+			}
+			sp = string(rune(r))
+		}
+		b.WriteString(strings.Repeat(sp, cnt))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,163 @@
+package pp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/pos"
+)
+
+var roundTripPrinters = []Printer{
+	{},
+	{CompactSpaces: true},
+	{TrimEmptyAttr: true},
+	// The positions are set to those of the parsed document.
+	{CompactSpaces: true, TrimEmptyAttr: true, Positions: pos.Map{}},
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("../testdata/*.html")
+	more, _ := filepath.Glob("../testdata/mf2/*.html")
+	files = append(files, more...)
+	if len(files) == 0 {
+		t.Fatal("no testdata")
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRoundTrip(t, file, string(src))
+	}
+
+	xss, err := os.ReadFile("../testdata/xss.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(string(xss), "\n") {
+		checkRoundTrip(t, "xss.txt:"+strconv.Itoa(i+1), line)
+	}
+}
+
+func checkRoundTrip(t *testing.T, name, src string) {
+	t.Helper()
+	doc, ps, err := pos.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp strings.Builder
+	html.Render(&exp, doc)
+
+	for i, p := range roundTripPrinters {
+		if p.Positions != nil {
+			p.Positions = ps
+		}
+		var b strings.Builder
+		p.Print(&b, doc)
+		doc2, err := Parse(strings.NewReader(b.String()))
+		if err != nil {
+			t.Errorf("%s printer[%d]: %v", name, i, err)
+			continue
+		}
+		// The positions are skipped, so doc2 has none.
+		var b2 strings.Builder
+		p.Print(&b2, doc2)
+		if p.Positions != nil {
+			p.Positions = nil
+			b.Reset()
+			p.Print(&b, doc)
+		}
+		if b2.String() != b.String() {
+			t.Errorf("%s printer[%d]: pp differs:\ngot:\n%s\nexp:\n%s", name, i, b2.String(), b.String())
+		}
+		var res strings.Builder
+		html.Render(&res, doc2)
+		if res.String() != exp.String() {
+			t.Errorf("%s printer[%d]: rendered trees differ", name, i)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := "" +
+		"T:DOC D:\n" +
+		"  T:DOCTYPE D:`html` A:[public=\"-//W3C//DTD HTML 4.01//EN\" system]\n" +
+		"  T:ELEM D:`html`\n" +
+		"    T:ELEM D:`head`\n" +
+		"    T:ELEM D:`body`\n" +
+		"      T:TEXT D:`a \\`b\\`\\n\\x01\\xff`\n" +
+		"      T:ELEM D:`svg` N:svg A:[xlink:href=\"#x\" xml\\:lang=\"en\" a\\=b\\ c]\n" +
+		"      T:RAW D:`<b>`\n" +
+		"      T:TEXT D:SPCx2,LF,U+00A0\n"
+
+	doc, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc.LastChild.LastChild
+	text, svg := body.FirstChild, body.FirstChild.NextSibling
+
+	checks := []struct {
+		name     string
+		got, exp any
+	}{
+		{"public id", doc.FirstChild.Attr[0].Val, "-//W3C//DTD HTML 4.01//EN"},
+		{"system id", doc.FirstChild.Attr[1].Key, "system"},
+		{"text", text.Data, "a `b`\n\x01\xff"},
+		{"namespace", svg.Namespace, "svg"},
+		{"attr namespace", svg.Attr[0].Namespace + "|" + svg.Attr[0].Key, "xlink|href"},
+		{"attr key", svg.Attr[1].Key, "xml:lang"},
+		{"odd attr key", svg.Attr[2].Key, "a=b c"},
+		{"raw", svg.NextSibling.Type, html.RawNode},
+		{"spaces", body.LastChild.Data, "  \n\u00a0"},
+	}
+	for _, c := range checks {
+		if c.got != c.exp {
+			t.Errorf("%s: got %q, exp %q", c.name, c.got, c.exp)
+		}
+	}
+
+	var b strings.Builder
+	Printer{CompactSpaces: true, TrimEmptyAttr: true}.Print(&b, doc)
+	if b.String() != src {
+		t.Errorf("mismatch:\ngot:\n%s\nexp:\n%s", b.String(), src)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tab := []string{
+		"",
+		"T:BOGUS D:``",
+		"T:ELEM D:`div",
+		"T:ELEM D:`div`\n   T:TEXT D:`x`",
+		"T:ELEM D:`div`\n    T:TEXT D:`x`",
+		"T:ELEM D:`div`\nT:ELEM D:`p`",
+		"T:TEXT D:WAT",
+		"T:ELEM D:`a` A:[href=\"x\"",
+		"T:ELEM D:`a` ... ×2 more",
+	}
+	for i, tc := range tab {
+		if _, err := Parse(strings.NewReader(tc)); err == nil {
+			t.Errorf("tc[%d]: expected error", i)
+		}
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, s := range []string{
+		"<p class=a>x</p>", "<svg><a xlink:href=#>t</a></svg>",
+		"<!DOCTYPE html PUBLIC \"-//W3C//DTD HTML 4.01//EN\"><b>\t\f</b>",
+		"<p title='a\"b`c'>\x00</p><!-- `c` -->", "<math><mi>x</mi></math>",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		checkRoundTrip(t, "fuzz", src)
+	})
+}
@@ -1,11 +1,15 @@
 package pp
 
 import (
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
 
 	"golang.org/x/net/html"
+
+	"github.com/wkhere/htmlx/pos"
+	"github.com/wkhere/htmlx/pred"
 )
 
 var nodeTypes = map[html.NodeType]string{
@@ -15,71 +19,164 @@ var nodeTypes = map[html.NodeType]string{
 	html.ElementNode:  "ELEM",
 	html.TextNode:     "TEXT",
 	html.CommentNode:  "COMMENT",
+	html.RawNode:      "RAW",
 }
 
+// Format selects the output of Printer.
+type Format int
+
+const (
+	// Text is the indented T:type D:data A:[attrs] listing.
+	Text Format = iota
+	// JSON is a single object, with the nodes nested in children.
+	// It is lossless; see Decode.
+	JSON
+	// NDJSON is one object per node, in document order,
+	// with id and parent instead of children.
+	NDJSON
+	// DOT is a Graphviz digraph of the tree.
+	DOT
+	// Mermaid is a Mermaid flowchart of the tree.
+	Mermaid
+)
+
 type Printer struct {
 	CompactSpaces bool
 	TrimEmptyAttr bool
+
+	// Positions, if set, prints L:line:col of the nodes it holds,
+	// see pos.Parse. In JSON formats the whole positions are written.
+	Positions pos.Map
+
+	// Paths adds XPath-like paths of the nodes in JSON formats.
+	Paths bool
+
+	Format Format
+
+	// MaxDepth limits the depth of the tree printed, replacing deeper
+	// nodes by a count; 0 means no limit. The limits and the options
+	// below do not apply to the JSON formats, which are lossless.
+	MaxDepth int
+
+	// MaxChildren limits the number of children printed per node,
+	// replacing the rest by a count; 0 means no limit.
+	MaxChildren int
+
+	// CollapseSimilar prints only the first of a run of sibling
+	// elements of the same structure, as told by element names
+	// and classes, replacing the rest by a count.
+	CollapseSimilar bool
+
+	// SkipSpaces leaves out whitespace-only text.
+	SkipSpaces bool
+
+	// Highlight marks the nodes it is true for in DOT and Mermaid graphs.
+	Highlight pred.Predicate
+
+	// Color styles the text format with ANSI escape sequences.
+	Color bool
+
+	// Width, if positive, is the line width the text format fits in,
+	// truncating long data and attribute values with an ellipsis.
+	Width int
 }
 
 func (p Printer) Print(w io.Writer, top *html.Node) {
+	switch p.Format {
+	case JSON:
+		p.printJSON(w, top)
+		return
+	case NDJSON:
+		p.printNDJSON(w, top)
+		return
+	case DOT:
+		p.printGraph(w, top, dot{})
+		return
+	case Mermaid:
+		p.printGraph(w, top, mermaid{})
+		return
+	}
 
 	var f func(*html.Node, int)
 
 	f = func(node *html.Node, i int) {
-		var dataRepr, attrRepr string
-		if p.CompactSpaces && len(strings.TrimSpace(node.Data)) == 0 {
-			dataRepr = ppSpaces(node.Data)
-		} else {
-			dataRepr = "`" + node.Data + "`"
-		}
-		attrRepr = ppAttr(node.Attr)
-
-		io.WriteString(w, strings.Repeat(" ", i*2))
-		io.WriteString(w, "T:")
-		io.WriteString(w, nodeTypes[node.Type])
-		io.WriteString(w, " D:")
-		io.WriteString(w, dataRepr)
-		if attrRepr != "" || !p.TrimEmptyAttr {
-			io.WriteString(w, " A:")
-			io.WriteString(w, attrRepr)
-		}
+		indent := strings.Repeat(" ", i*2)
+		io.WriteString(w, indent)
+		io.WriteString(w, p.render(p.segments(node), p.Width-len(indent)))
 		w.Write([]byte{'\n'})
 
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			f(c, i+1)
+		for _, e := range p.entries(node, i) {
+			if e.node != nil {
+				f(e.node, i+1)
+				continue
+			}
+			io.WriteString(w, indent+"  ")
+			io.WriteString(w, p.render([]seg{{raw: e.summary(), color: dim}}, 0))
+			w.Write([]byte{'\n'})
 		}
 	}
 
 	f(top, 0)
 }
 
-func ppAttr(aa []html.Attribute) string {
-	if len(aa) == 0 {
-		return ""
+// label describes a single node, like T:ELEM D:`div` A:[id="1"].
+func (p Printer) label(node *html.Node) string {
+	return p.render(p.segments(node), 0)
+}
+
+func (p Printer) segments(node *html.Node) []seg {
+	ss := []seg{
+		{raw: "T:", color: dim},
+		{raw: nodeTypes[node.Type], color: typeColors[node.Type]},
+		{raw: " D:", color: dim},
 	}
-	b := new(strings.Builder)
-	b.WriteByte('[')
-	bppAttr1(b, aa[0])
-	for _, a := range aa[1:] {
-		b.WriteByte(' ')
-		bppAttr1(b, a)
+	switch {
+	case p.CompactSpaces && len(strings.TrimSpace(node.Data)) == 0:
+		ss = append(ss, seg{raw: ppSpaces(node.Data), color: spaceColor})
+	case node.Type == html.ElementNode:
+		ss = append(ss, seg{raw: "`"},
+			seg{raw: node.Data, esc: escData, color: tagColor},
+			seg{raw: "`"})
+	default:
+		ss = append(ss, seg{raw: "`"},
+			seg{raw: node.Data, esc: escData, cut: true, color: dataColors[node.Type]},
+			seg{raw: "`"})
+	}
+
+	if node.Namespace != "" {
+		ss = append(ss, seg{raw: " N:", color: dim},
+			seg{raw: node.Namespace, esc: escKey, color: tagColor})
 	}
-	b.WriteByte(']')
-	return b.String()
-}
 
-func bppAttr1(b *strings.Builder, a html.Attribute) {
-	if a.Namespace != "" {
-		b.WriteString(a.Namespace)
-		b.WriteByte(':')
+	if len(node.Attr) > 0 || !p.TrimEmptyAttr {
+		ss = append(ss, seg{raw: " A:", color: dim})
+	}
+	for i, a := range node.Attr {
+		sep := " "
+		if i == 0 {
+			sep = "["
+		}
+		k := escKey(a.Key)
+		if a.Namespace != "" {
+			k = escKey(a.Namespace) + ":" + k
+		}
+		ss = append(ss, seg{raw: sep}, seg{raw: k, color: keyColor})
+		if a.Val != "" {
+			ss = append(ss, seg{raw: `="`},
+				seg{raw: a.Val, esc: escAttr, cut: true, color: valColor},
+				seg{raw: `"`})
+		}
+	}
+	if len(node.Attr) > 0 {
+		ss = append(ss, seg{raw: "]"})
 	}
-	b.WriteString(a.Key)
-	if a.Val != "" {
-		b.WriteString(`="`)
-		b.WriteString(a.Val)
-		b.WriteByte('"')
+
+	if p.Positions != nil {
+		if np := p.Positions.Of(node); np.IsValid() {
+			ss = append(ss, seg{raw: " L:" + np.Start.Start.String(), color: dim})
+		}
 	}
+	return ss
 }
 
 // ppSpaces pretty prints various whitespaces, possibly with counters.
@@ -102,8 +199,10 @@ func ppSpaces(s string) string {
 			t = "TAB"
 		case ' ':
 			t = "SPC"
+		case '\f':
+			t = "FF"
 		default:
-			t = "WS"
+			t = fmt.Sprintf("U+%04X", c)
 		}
 		a = append(a, token{val: t, cnt: 1})
 	}
@@ -115,7 +214,7 @@ func ppSpaces(s string) string {
 	r := a[:1]
 	i := 0
 	for _, tok := range a[1:] {
-		if r[i].val == tok.val && tok.val != "WS" {
+		if r[i].val == tok.val {
 			r[i].cnt++
 		} else {
 			r = append(r, tok)
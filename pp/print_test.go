@@ -0,0 +1,122 @@
+package pp
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestPrint(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(
+		"<p title='say \"hi\"\\n'>a `quoted`\tline\n\x01end</p>\n" +
+			`<a href="https://example.com/a/very/long/path/to/somewhere" id=x>` +
+			`short text, but a very long line anyway</a>`))
+	body := doc.FirstChild.LastChild
+
+	tab := []struct {
+		p   Printer
+		exp string
+	}{
+		{Printer{CompactSpaces: true, TrimEmptyAttr: true}, "" +
+			"T:ELEM D:`body`\n" +
+			"  T:ELEM D:`p` A:[title=\"say \\\"hi\\\"\\\\n\"]\n" +
+			"    T:TEXT D:`a \\`quoted\\`\\tline\\n\\x01end`\n" +
+			"  T:TEXT D:LF\n" +
+			"  T:ELEM D:`a` A:[href=\"https://example.com/a/very/long/path/to/somewhere\" id=\"x\"]\n" +
+			"    T:TEXT D:`short text, but a very long line anyway`\n"},
+		{Printer{CompactSpaces: true, TrimEmptyAttr: true, Width: 40}, "" +
+			"T:ELEM D:`body`\n" +
+			"  T:ELEM D:`p` A:[title=\"say \\\"hi\\\"\\\\n\"]\n" +
+			"    T:TEXT D:`a \\`quoted\\`\\tline\\n\\x01…`\n" +
+			"  T:TEXT D:LF\n" +
+			"  T:ELEM D:`a` A:[href=\"https:/…\" id=\"x\"]\n" +
+			"    T:TEXT D:`short text, but a very l…`\n"},
+	}
+
+	for i, tc := range tab {
+		var b strings.Builder
+		tc.p.Print(&b, body)
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
+
+func TestPrintColor(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader("<p class=a>x</p> "))
+	body := doc.FirstChild.LastChild
+
+	p := Printer{CompactSpaces: true, TrimEmptyAttr: true}
+	var plain, color strings.Builder
+	p.Print(&plain, body)
+	p.Color = true
+	p.Print(&color, body)
+
+	res := color.String()
+	for _, s := range []string{"\x1b[1;34mELEM\x1b[0m", "\x1b[1;36mp\x1b[0m",
+		"\x1b[33mclass\x1b[0m", "\x1b[32ma\x1b[0m", "\x1b[2;33mSPC\x1b[0m"} {
+		if !strings.Contains(res, s) {
+			t.Errorf("no %q in:\n%q", s, res)
+		}
+	}
+	if res := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(res, ""); res != plain.String() {
+		t.Errorf("mismatch:\ngot:\n%s\nexp:\n%s", res, plain.String())
+	}
+}
+
+func TestPrintLimits(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("<ul>\n")
+	for i := 0; i < 5; i++ {
+		src.WriteString("<li class='item x'><a href=#>item</a></li>\n")
+	}
+	src.WriteString("<li class='x item'><a>other</a><b></b></li>\n<li>last</li></ul><p><b>deep</b></p><p>1</p><p>2</p>")
+	doc, _ := html.Parse(strings.NewReader(src.String()))
+	body := doc.FirstChild.LastChild
+
+	tab := []struct {
+		p   Printer
+		exp string
+	}{
+		{Printer{TrimEmptyAttr: true, CollapseSimilar: true, SkipSpaces: true}, "" +
+			"T:ELEM D:`body`\n" +
+			"  T:ELEM D:`ul`\n" +
+			"    T:ELEM D:`li` A:[class=\"item x\"]\n" +
+			"      T:ELEM D:`a` A:[href=\"#\"]\n" +
+			"        T:TEXT D:`item`\n" +
+			"    ... ×4 more similar\n" +
+			"    T:ELEM D:`li` A:[class=\"x item\"]\n" +
+			"      T:ELEM D:`a`\n" +
+			"        T:TEXT D:`other`\n" +
+			"      T:ELEM D:`b`\n" +
+			"    T:ELEM D:`li`\n" +
+			"      T:TEXT D:`last`\n" +
+			"  T:ELEM D:`p`\n" +
+			"    T:ELEM D:`b`\n" +
+			"      T:TEXT D:`deep`\n" +
+			"  T:ELEM D:`p`\n" +
+			"    T:TEXT D:`1`\n" +
+			"  ... ×1 more similar\n"},
+		{Printer{TrimEmptyAttr: true, CompactSpaces: true, MaxDepth: 2, MaxChildren: 2}, "" +
+			"T:ELEM D:`body`\n" +
+			"  T:ELEM D:`ul`\n" +
+			"    T:TEXT D:LF\n" +
+			"    T:ELEM D:`li` A:[class=\"item x\"]\n" +
+			"      ... ×1 more\n" +
+			"    ... ×12 more\n" +
+			"  T:ELEM D:`p`\n" +
+			"    T:ELEM D:`b`\n" +
+			"      ... ×1 more\n" +
+			"  ... ×2 more\n"},
+	}
+
+	for i, tc := range tab {
+		var b strings.Builder
+		tc.p.Print(&b, body)
+		if res := b.String(); res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package pp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ANSI SGR parameters of the styled parts.
+const (
+	dim        = "2"
+	tagColor   = "1;36"
+	spaceColor = "2;33"
+	keyColor   = "33"
+	valColor   = "32"
+)
+
+var typeColors = map[html.NodeType]string{
+	html.ErrorNode:    "1;31",
+	html.DocumentNode: "35",
+	html.DoctypeNode:  "35",
+	html.ElementNode:  "1;34",
+	html.TextNode:     "32",
+	html.CommentNode:  "90",
+	html.RawNode:      "31",
+}
+
+var dataColors = map[html.NodeType]string{
+	html.CommentNode: "90",
+	html.DoctypeNode: "35",
+}
+
+// minCut is the shortest width a value is truncated to,
+// ellipsis included.
+const minCut = 8
+
+// seg is a piece of a line, styled as a whole.
+type seg struct {
+	raw   string
+	esc   func(string) string // escaping raw, if needed
+	cut   bool                // may be truncated
+	ell   bool                // was truncated
+	color string
+}
+
+func (s seg) text() string {
+	t := s.raw
+	if s.esc != nil {
+		t = s.esc(t)
+	}
+	if s.ell {
+		t += "…"
+	}
+	return t
+}
+
+func (s seg) width() int {
+	return utf8.RuneCountInString(s.text())
+}
+
+// truncate shortens the segment to at most w wide.
+func (s seg) truncate(w int) seg {
+	n := 1 // the ellipsis
+	for i, r := range s.raw {
+		rw := 1
+		if s.esc != nil {
+			rw = utf8.RuneCountInString(s.esc(string(r)))
+		}
+		if n+rw > w {
+			s.raw, s.ell = s.raw[:i], true
+			return s
+		}
+		n += rw
+	}
+	return s
+}
+
+// render joins the segments, styled if asked for. If width is positive,
+// the cuttable segments are truncated, longest first, to fit in it.
+func (p Printer) render(ss []seg, width int) string {
+	if p.Width > 0 && width > 0 {
+		fit(ss, width)
+	}
+	var b strings.Builder
+	for _, s := range ss {
+		if p.Color && s.color != "" && s.raw != "" {
+			b.WriteString("\x1b[" + s.color + "m" + s.text() + "\x1b[0m")
+			continue
+		}
+		b.WriteString(s.text())
+	}
+	return b.String()
+}
+
+func fit(ss []seg, width int) {
+	total := 0
+	for _, s := range ss {
+		total += s.width()
+	}
+	for total > width {
+		j := -1
+		for i, s := range ss {
+			if s.cut && s.width() > minCut && (j < 0 || s.width() > ss[j].width()) {
+				j = i
+			}
+		}
+		if j < 0 {
+			return
+		}
+		w := ss[j].width()
+		ss[j] = ss[j].truncate(max(minCut, w-(total-width)))
+		total -= w - ss[j].width()
+	}
+}
+
+// escData makes data unambiguous between backticks, on a single line.
+func escData(s string) string {
+	return escape(s, "`")
+}
+
+// escAttr makes attribute values unambiguous between double quotes.
+func escAttr(s string) string {
+	return escape(s, `"`)
+}
+
+// escKey makes attribute keys and namespaces unambiguous.
+func escKey(s string) string {
+	return escape(s, `:= ]"`)
+}
+
+// escape escapes backslashes, control characters
+// and the special characters given.
+func escape(s string, special string) string {
+	if utf8.ValidString(s) && !strings.ContainsFunc(s, func(r rune) bool {
+		return r < ' ' || r == 0x7f || r == '\\' || strings.ContainsRune(special, r)
+	}) {
+		return s
+	}
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				fmt.Fprintf(&b, `\x%02x`, s[i])
+			} else {
+				b.WriteRune(r)
+			}
+		case r == '\\' || strings.ContainsRune(special, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package pred
+
+import (
+	"github.com/andybalholm/cascadia"
+)
+
+// CSS compiles a CSS selector group, like "ul.items > li, #main a[href]".
+func CSS(sel string) (Predicate, error) {
+	m, err := cascadia.ParseGroup(sel)
+	if err != nil {
+		return nil, err
+	}
+	return m.Match, nil
+}
+
+// MustCSS is like CSS but panics if the selector is invalid.
+func MustCSS(sel string) Predicate {
+	p, err := CSS(sel)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
@@ -0,0 +1,103 @@
+package readability
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// meta returns the content of the first <meta> whose name or property
+// is one of the keys, tried in order.
+func meta(doc htmlx.Finder, keys ...string) string {
+	for _, k := range keys {
+		m := doc.Find(pred.Element(atom.Meta, func(h *html.Node) bool {
+			a := attr.L(h.Attr)
+			return a.HasVal("name", k) || a.HasVal("property", k) ||
+				a.HasVal("itemprop", k)
+		}))
+		if v, ok := m.Attr().Val("content"); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+var titleSeps = []string{" | ", " - ", " – ", " — ", " :: ", " » "}
+
+func title(doc htmlx.Finder) string {
+	if t := meta(doc, "og:title", "twitter:title"); t != "" {
+		return t
+	}
+
+	t := strings.Join(strings.Fields(innerTextOf(doc.Find(pred.Element(atom.Title)))), " ")
+	if t == "" {
+		return innerTextOf(doc.Find(pred.Element(atom.H1)))
+	}
+
+	// Drop the site name from titles like "Story | Site".
+	for _, sep := range titleSeps {
+		if i := strings.LastIndex(t, sep); i > 0 {
+			if head := t[:i]; len(strings.Fields(head)) >= 3 {
+				return head
+			}
+		}
+	}
+	return t
+}
+
+func byline(doc htmlx.Finder) string {
+	if s := meta(doc, "author", "article:author", "dc.creator"); s != "" &&
+		!strings.Contains(s, "://") {
+		return s
+	}
+
+	f := doc.Find(func(h *html.Node) bool {
+		if h.Type != html.ElementNode {
+			return false
+		}
+		a := attr.L(h.Attr)
+		return a.HasVal("rel", "author") || a.HasVal("itemprop", "author") ||
+			a.HasClassCond(bylineRe.MatchString) || a.HasIDCond(bylineRe.MatchString)
+	})
+	s := strings.Join(strings.Fields(innerTextOf(f)), " ")
+	if len(s) > 100 {
+		return ""
+	}
+	return s
+}
+
+func published(doc htmlx.Finder) string {
+	return meta(doc,
+		"article:published_time", "datePublished", "date", "dc.date",
+		"pubdate", "og:published_time",
+	)
+}
+
+func timeIn(content htmlx.Finder) string {
+	t := content.Find(pred.Element(atom.Time, pred.AttrCond("datetime", nonBlank)))
+	v, _ := t.Attr().Val("datetime")
+	return v
+}
+
+func leadImage(doc, content htmlx.Finder) string {
+	if s := meta(doc, "og:image", "twitter:image"); s != "" {
+		return s
+	}
+	img := content.Find(pred.Element(atom.Img, pred.AttrCond("src", nonBlank)))
+	v, _ := img.Attr().Val("src")
+	return v
+}
+
+func innerTextOf(f htmlx.Finder) string {
+	if f.IsEmpty() {
+		return ""
+	}
+	return innerText(f.Node)
+}
+
+func nonBlank(s string) bool { return strings.TrimSpace(s) != "" }
@@ -0,0 +1,253 @@
+// Package readability extracts the main content of an article page,
+// leaving out navigation, ads, comments and footers.
+//
+// Candidate containers are scored by the paragraphs they hold,
+// their text and link density and class/id hints, in the spirit
+// of Arc90's readability.
+package readability
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/pred"
+)
+
+type Article struct {
+	Content   htmlx.Finder
+	Title     string
+	Byline    string
+	Image     string
+	Published string
+}
+
+// Text returns the non-blank text of the content, one text node per line.
+func (a Article) Text() string {
+	var b strings.Builder
+	for f := range htmlx.AllText(a.Content) {
+		b.WriteString(strings.TrimSpace(f.Data))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var (
+	unlikelyRe = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|nav|cookie`)
+	maybeRe    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	negativeRe = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav`)
+	positiveRe = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	bylineRe   = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+)
+
+const minParagraphLen = 25
+
+type scorer struct {
+	scores map[*html.Node]float64
+}
+
+// Extract finds the best content container of the document
+// together with the article metadata.
+// Content is empty if no candidate was found.
+func Extract(doc htmlx.Finder) Article {
+	a := Article{
+		Title:     title(doc),
+		Byline:    byline(doc),
+		Published: published(doc),
+	}
+	if doc.IsEmpty() {
+		return a
+	}
+
+	s := scorer{scores: map[*html.Node]float64{}}
+	s.walk(doc.Node)
+	a.Content = htmlx.FinderFromNode(s.best())
+
+	a.Image = leadImage(doc, a.Content)
+	if a.Published == "" {
+		a.Published = timeIn(a.Content)
+	}
+	return a
+}
+
+func (s *scorer) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Nav,
+			atom.Footer, atom.Aside, atom.Form:
+			return
+		}
+		if unlikely(n) {
+			return
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td:
+			s.scoreParagraph(n)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s.walk(c)
+	}
+}
+
+func (s *scorer) scoreParagraph(n *html.Node) {
+	text := innerText(n)
+	if len(text) < minParagraphLen {
+		return
+	}
+	score := 1 + float64(strings.Count(text, ",")) +
+		math.Min(float64(len(text))/100, 3)
+
+	parent := n.Parent
+	for level := 0; parent != nil && level < 3; level++ {
+		if parent.Type != html.ElementNode {
+			break
+		}
+		if _, ok := s.scores[parent]; !ok {
+			s.scores[parent] = initialScore(parent)
+		}
+		switch level {
+		case 0:
+			s.scores[parent] += score
+		case 1:
+			s.scores[parent] += score / 2
+		default:
+			s.scores[parent] += score / (float64(level) * 3)
+		}
+		parent = parent.Parent
+	}
+}
+
+func (s *scorer) best() (top *html.Node) {
+	var topScore float64
+	for n, score := range s.scores {
+		score *= 1 - linkDensity(n)
+		if top == nil || score > topScore ||
+			score == topScore && isBefore(n, top) {
+			top, topScore = n, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	// Prefer the parent when the content is split among siblings
+	// scoring nearly as high as the top candidate.
+	if p := top.Parent; p != nil && p.Type == html.ElementNode &&
+		p.DataAtom != atom.Body && p.DataAtom != atom.Html {
+		var close int
+		for c := p.FirstChild; c != nil; c = c.NextSibling {
+			if score, ok := s.scores[c]; ok && c != top &&
+				score*(1-linkDensity(c)) >= topScore*0.75 {
+				close++
+			}
+		}
+		if close >= 1 {
+			return p
+		}
+	}
+	return top
+}
+
+func initialScore(n *html.Node) (score float64) {
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt,
+		atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	return score + classWeight(n)
+}
+
+func classWeight(n *html.Node) (w float64) {
+	a := attr.L(n.Attr)
+	for _, hint := range []func(func(string) bool) bool{
+		a.HasClassCond, a.HasIDCond,
+	} {
+		if hint(negativeRe.MatchString) {
+			w -= 25
+		}
+		if hint(positiveRe.MatchString) {
+			w += 25
+		}
+	}
+	return w
+}
+
+func unlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Body, atom.Html, atom.Article, atom.Main:
+		return false
+	}
+	a := attr.L(n.Attr)
+	match := func(re *regexp.Regexp) bool {
+		return a.HasClassCond(re.MatchString) || a.HasIDCond(re.MatchString)
+	}
+	if r, _ := a.Val("role"); r == "complementary" || r == "navigation" {
+		return true
+	}
+	return match(unlikelyRe) && !match(maybeRe)
+}
+
+// linkDensity is the ratio of text inside links to all the text.
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	var links int
+	for a := range htmlx.FinderFromNode(n).FindAll(pred.Element(atom.A)) {
+		links += len(innerText(a.Node))
+	}
+	return float64(links) / float64(total)
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	for f := range htmlx.AllText(htmlx.FinderFromNode(n)) {
+		if p := f.Parent(); !p.IsEmpty() &&
+			(p.DataAtom == atom.Script || p.DataAtom == atom.Style) {
+			continue
+		}
+		b.WriteString(strings.Join(strings.Fields(f.Data), " "))
+		b.WriteByte(' ')
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// isBefore reports whether a precedes b in document order.
+func isBefore(a, b *html.Node) bool {
+	found := false
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n == a {
+			found = true
+			return true
+		}
+		if n == b {
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	root := a
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	walk(root)
+	return found
+}
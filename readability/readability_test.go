@@ -0,0 +1,101 @@
+package readability
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+)
+
+func TestExtractArticle(t *testing.T) {
+	a := Extract(testdata(t, "article.html"))
+
+	if a.Content.IsEmpty() {
+		t.Fatal("no content found")
+	}
+	if cl, _ := a.Content.Attr().ClassList(); len(cl) == 0 || cl[0] != "entry-content" {
+		t.Errorf("content: got %v, exp entry-content div", a.Content.Attr())
+	}
+
+	text := a.Text()
+	for _, s := range []string{"Parsing is the first step", "Trees are everywhere", "line and column"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("content lacks %q", s)
+		}
+	}
+	for _, s := range []string{"Archive", "Great post", "Buy our", "Copyright", "tracking"} {
+		if strings.Contains(text, s) {
+			t.Errorf("content has unwanted %q", s)
+		}
+	}
+
+	tab := []struct{ name, got, exp string }{
+		{"title", a.Title, "Why Parsers Matter"},
+		{"byline", a.Byline, "Ada Lovelace"},
+		{"image", a.Image, "/img/tree.png"},
+		{"published", a.Published, "2024-03-05T10:00:00Z"},
+	}
+	for _, tc := range tab {
+		if tc.got != tc.exp {
+			t.Errorf("%s: got %q, exp %q", tc.name, tc.got, tc.exp)
+		}
+	}
+}
+
+func TestExtractGov(t *testing.T) {
+	a := Extract(testdata(t, "gatesofvienna.html"))
+
+	// The page is a saved view-source listing: one big table
+	// holding the source lines.
+	if a.Content.IsEmpty() {
+		t.Fatal("no content found")
+	}
+	if a.Content.DataAtom != atom.Tbody && a.Content.DataAtom != atom.Table {
+		t.Errorf("content: got <%s>, exp the listing table", a.Content.Data)
+	}
+	if a.Title != "" || a.Byline != "" {
+		t.Errorf("unexpected metadata: title %q, byline %q", a.Title, a.Byline)
+	}
+}
+
+func TestExtractEmpty(t *testing.T) {
+	a := Extract(htmlx.Finder{})
+	if !a.Content.IsEmpty() || a.Text() != "" {
+		t.Error("expected empty article")
+	}
+
+	f, _ := htmlx.FinderFromString(`<p>short</p>`)
+	if a := Extract(f); !a.Content.IsEmpty() {
+		t.Errorf("expected no content, got %v", a.Content)
+	}
+}
+
+func TestExtractSidebar(t *testing.T) {
+	long := strings.Repeat("Other stories, other places, other people. ", 20)
+	f, _ := htmlx.FinderFromString(`<div id="page">` +
+		`<div id="story"><p>The story itself, told in a single paragraph, short but real.</p></div>` +
+		`<div id="side"><p class="sidebar">` + long + `</p><p class="comment">` + long + `</p></div>` +
+		`</div>`)
+	a := Extract(f)
+	if id, _ := a.Content.Attr().Val("id"); id != "story" {
+		t.Errorf("content: got %v, exp the story div", a.Content.Attr())
+	}
+}
+
+func testdata(t *testing.T, file string) htmlx.Finder {
+	t.Helper()
+	r, err := os.Open(filepath.Join("..", "testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	f, err := htmlx.FinderFromData(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
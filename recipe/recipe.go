@@ -0,0 +1,386 @@
+// Package recipe extracts structured data from HTML documents
+// as described by declarative recipes, written in YAML or JSON:
+//
+//	name: products
+//	fields:
+//	  - name: products
+//	    select: div.product
+//	    list: true
+//	    fields:
+//	      - name: title
+//	        select: h2
+//	        trim: true
+//	        required: true
+//	      - name: url
+//	        select: a
+//	        attr: href
+//	      - name: price
+//	        select: .price
+//	        regexp: '([0-9.,]+)'
+//	        type: float
+//
+// The result is a JSON object with the fields in the recipe order.
+package recipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/internal/extract"
+	"github.com/wkhere/htmlx/pred"
+)
+
+// Recipe describes the fields to extract from a document.
+type Recipe struct {
+	Name   string   `json:"name,omitempty" yaml:"name"`
+	Fields []*Field `json:"fields" yaml:"fields"`
+}
+
+// Field describes a value to extract. The value is the text of the
+// node the Select matches, its attribute, or its HTML, processed
+// by Trim and Regexp, then converted to the Type; a Field with nested
+// Fields gives an object instead.
+type Field struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Select is a CSS selector matched under the node of the enclosing
+	// field, or of the document; if empty, that node itself is taken.
+	Select string `json:"select,omitempty" yaml:"select"`
+
+	// Attr, if set, names the attribute to take instead of the text.
+	Attr string `json:"attr,omitempty" yaml:"attr"`
+
+	// HTML takes the HTML of the node instead of the text.
+	HTML bool `json:"html,omitempty" yaml:"html"`
+
+	// Trim trims the value and collapses the whitespace within.
+	Trim bool `json:"trim,omitempty" yaml:"trim"`
+
+	// Regexp, if set, takes the part of the value it matches,
+	// or the match of its first group if it has one.
+	Regexp string `json:"regexp,omitempty" yaml:"regexp"`
+
+	// Type is string, the default, int, float, bool or date.
+	// The numbers may have comma or space thousands separators.
+	Type string `json:"type,omitempty" yaml:"type"`
+
+	// Layout is the time.Parse layout of a date, RFC 3339 if empty.
+	Layout string `json:"layout,omitempty" yaml:"layout"`
+
+	// List takes all the matches of Select, giving a list.
+	List bool `json:"list,omitempty" yaml:"list"`
+
+	// Required makes a missing value an error; a value is missing
+	// if nothing matches or it is empty, and a list if it is empty.
+	Required bool `json:"required,omitempty" yaml:"required"`
+
+	// Default is the value of a missing field which is not required;
+	// null otherwise.
+	Default any `json:"default,omitempty" yaml:"default"`
+
+	// Fields, if set, describe an object to extract from each match.
+	Fields []*Field `json:"fields,omitempty" yaml:"fields"`
+
+	sel pred.Predicate
+	re  *regexp.Regexp
+}
+
+// ErrMissing is the error of a required field having no value.
+var ErrMissing = errors.New("required value missing")
+
+// FieldError is an error of extracting a field, at a path
+// like products[2].price.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Errors lists the field errors of an extraction.
+type Errors []*FieldError
+
+func (ee Errors) Error() string {
+	s := make([]string, len(ee))
+	for i, e := range ee {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (ee Errors) Unwrap() []error {
+	errs := make([]error, len(ee))
+	for i, e := range ee {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Parse reads a recipe in YAML, or in JSON if it begins with {,
+// checking its fields.
+func Parse(data []byte) (*Recipe, error) {
+	r := new(Recipe)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(r); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(r); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.Fields) == 0 {
+		return nil, errors.New("recipe has no fields")
+	}
+	if err := compile(r.Fields, ""); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load reads a recipe from a file.
+func Load(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func compile(fields []*Field, prefix string) error {
+	seen := map[string]bool{}
+	for i, f := range fields {
+		if f == nil {
+			return fmt.Errorf("%sfield %d is empty", prefix, i)
+		}
+		if f.Name == "" {
+			return fmt.Errorf("%sfield %d has no name", prefix, i)
+		}
+		path := prefix + f.Name
+		if seen[f.Name] {
+			return fmt.Errorf("%s: duplicate field", path)
+		}
+		seen[f.Name] = true
+
+		if f.Select != "" {
+			p, err := pred.CSS(f.Select)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			f.sel = p
+		}
+		if f.Regexp != "" {
+			re, err := regexp.Compile(f.Regexp)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			f.re = re
+		}
+		switch f.Type {
+		case "", "string", "int", "float", "bool", "date":
+		default:
+			return fmt.Errorf("%s: unknown type %q", path, f.Type)
+		}
+		if f.Layout != "" && f.Type != "date" {
+			return fmt.Errorf("%s: layout needs the date type", path)
+		}
+		if f.Attr != "" && f.HTML {
+			return fmt.Errorf("%s: both attr and html set", path)
+		}
+		if len(f.Fields) > 0 {
+			if f.Attr != "" || f.HTML || f.Trim || f.Regexp != "" || f.Type != "" {
+				return fmt.Errorf("%s: a field with fields takes no value options", path)
+			}
+			if err := compile(f.Fields, path+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Extract runs the recipe on the document, returning the JSON
+// of the extracted object. If some fields fail, the error is Errors
+// and they are null in the object.
+func (r *Recipe) Extract(f htmlx.Finder) (json.RawMessage, error) {
+	var x extraction
+	obj := x.object(r.Fields, f.Node, "")
+	data, err := extract.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(x.errs) > 0 {
+		return data, x.errs
+	}
+	return data, nil
+}
+
+type extraction struct {
+	errs Errors
+}
+
+func (x *extraction) fail(path string, err error) {
+	x.errs = append(x.errs, &FieldError{path, err})
+}
+
+func (x *extraction) object(fields []*Field, n *html.Node, prefix string) extract.Object {
+	obj := make(extract.Object, len(fields))
+	for i, f := range fields {
+		obj[i] = extract.Member{Key: f.Name, Val: x.field(f, n, prefix+f.Name)}
+	}
+	return obj
+}
+
+func (x *extraction) field(f *Field, n *html.Node, path string) any {
+	nodes := matches(f, n)
+
+	if f.List {
+		list := []any{}
+		for _, m := range nodes {
+			p := fmt.Sprintf("%s[%d]", path, len(list))
+			if v, ok := x.value(f, m, p); ok {
+				list = append(list, v)
+			}
+		}
+		if len(list) == 0 && f.Required {
+			x.fail(path, ErrMissing)
+		}
+		return list
+	}
+
+	if len(nodes) > 0 {
+		if v, ok := x.value(f, nodes[0], path); ok {
+			return v
+		}
+	}
+	if f.Required {
+		x.fail(path, ErrMissing)
+		return nil
+	}
+	return f.Default
+}
+
+// value extracts the field from a matched node, telling if there
+// is one; a failed conversion is reported, giving null.
+func (x *extraction) value(f *Field, n *html.Node, path string) (any, bool) {
+	if len(f.Fields) > 0 {
+		return x.object(f.Fields, n, path+"."), true
+	}
+
+	var s string
+	switch {
+	case f.Attr != "":
+		v, ok := htmlx.FinderFromNode(n).Attr().Val(f.Attr)
+		if !ok {
+			return nil, false
+		}
+		s = v
+	case f.HTML:
+		var b strings.Builder
+		html.Render(&b, n)
+		s = b.String()
+	default:
+		s = extract.Content(n)
+	}
+	if f.Trim {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	if f.re != nil {
+		m := f.re.FindStringSubmatch(s)
+		if m == nil {
+			return nil, false
+		}
+		s = m[min(1, len(m)-1)]
+	}
+	if s == "" {
+		return nil, false
+	}
+
+	v, err := convert(s, f)
+	if err != nil {
+		x.fail(path, err)
+		return nil, true
+	}
+	return v, true
+}
+
+func matches(f *Field, n *html.Node) (nodes []*html.Node) {
+	if f.sel == nil {
+		return []*html.Node{n}
+	}
+	under := func(c *html.Node) bool { return c != n && f.sel(c) }
+	if !f.List {
+		if m := htmlx.FinderFromNode(n).Find(under); !m.IsEmpty() {
+			nodes = append(nodes, m.Node)
+		}
+		return nodes
+	}
+	for m := range htmlx.FinderFromNode(n).FindAll(under) {
+		nodes = append(nodes, m.Node)
+	}
+	return nodes
+}
+
+func convert(s string, f *Field) (any, error) {
+	number := func() string {
+		return strings.Map(func(r rune) rune {
+			switch r {
+			case ',', ' ', '\u00a0', '\u202f':
+				return -1
+			}
+			return r
+		}, strings.TrimSpace(s))
+	}
+	switch f.Type {
+	case "int":
+		v, err := strconv.ParseInt(number(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not an int: %q", s)
+		}
+		return v, nil
+	case "float":
+		v, err := strconv.ParseFloat(number(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a float: %q", s)
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("not a bool: %q", s)
+		}
+		return v, nil
+	case "date":
+		layout := f.Layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		v, err := time.Parse(layout, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("not a date: %q", s)
+		}
+		return v, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,143 @@
+package recipe
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+)
+
+const doc = `<h1> Shop </h1>
+<div class="product" id="p1">
+	<h2>  Red
+		shoes </h2>
+	<a href="/red">more</a>
+	<span class="price">$1,299.50</span>
+	<span class="stock">12 left</span>
+	<time datetime="2024-03-05">March 5</time>
+	<ul><li>leather<li>red</ul>
+</div>
+<div class="product" id="p2">
+	<h2></h2>
+	<span class="price">call us</span>
+	<span class="stock">none</span>
+</div>`
+
+func TestExtract(t *testing.T) {
+	tab := []struct {
+		recipe string
+		exp    string
+		errs   string
+	}{
+		{`
+fields:
+  - name: title
+    select: h1
+    trim: true
+  - name: count
+    select: div.product
+    list: true
+    attr: id
+`, `{"title":"Shop","count":["p1","p2"]}`, ""},
+
+		{`
+fields:
+  - name: products
+    select: .product
+    list: true
+    fields:
+      - {name: title, select: h2, trim: true, required: true}
+      - {name: url, select: a, attr: href, default: "/"}
+      - {name: price, select: .price, regexp: '[0-9.,]+', type: float}
+      - {name: stock, select: .stock, regexp: '(\d+) left', type: int}
+      - {name: date, select: time, attr: datetime, type: date, layout: "2006-01-02"}
+      - {name: tags, select: li, list: true}
+`, `{"products":[` +
+			`{"title":"Red shoes","url":"/red","price":1299.5,"stock":12,` +
+			`"date":"2024-03-05T00:00:00Z","tags":["leather","red"]},` +
+			`{"title":null,"url":"/","price":null,"stock":null,"date":null,"tags":[]}]}`,
+			"products[1].title: required value missing"},
+
+		{`{"fields": [
+	{"name": "p", "select": "#p1", "fields": [
+		{"name": "id", "attr": "id"},
+		{"name": "html", "select": "li", "html": true},
+		{"name": "n", "select": ".stock", "type": "int"},
+		{"name": "none", "select": "table", "list": true, "required": true}
+	]}
+]}`, `{"p":{"id":"p1","html":"<li>leather</li>","n":null,"none":[]}}`,
+			"p.n: not an int: \"12 left\"\np.none: required value missing"},
+	}
+	for i, tc := range tab {
+		r, err := Parse([]byte(tc.recipe))
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+			continue
+		}
+		f, _ := htmlx.FinderFromString(doc)
+		res, err := r.Extract(f)
+		var errs string
+		if err != nil {
+			var ee Errors
+			if !errors.As(err, &ee) {
+				t.Errorf("tc[%d]: %v", i, err)
+				continue
+			}
+			errs = ee.Error()
+		}
+		if string(res) != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+		if errs != tc.errs {
+			t.Errorf("tc[%d] errors mismatch:\ngot:\n%s\nexp:\n%s", i, errs, tc.errs)
+		}
+	}
+}
+
+func TestExtractMissing(t *testing.T) {
+	r, err := Parse([]byte("fields:\n  - {name: x, select: p, required: true}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := htmlx.FinderFromString("<div></div>")
+	_, err = r.Extract(f)
+	if !errors.Is(err, ErrMissing) {
+		t.Errorf("got %v, exp ErrMissing", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tab := []struct {
+		recipe string
+		exp    string
+	}{
+		{"name: x\n", "recipe has no fields"},
+		{"fields:\n  - {select: p}\n", "field 0 has no name"},
+		{"fields:\n  - {name: a}\n  - {name: a}\n", "a: duplicate field"},
+		{"fields:\n  - {name: a, select: 'p >'}\n", "a: "},
+		{"fields:\n  - {name: a, regexp: '('}\n", "a: error parsing regexp: "},
+		{"fields:\n  - {name: a, type: money}\n", "a: unknown type \"money\""},
+		{"fields:\n  - {name: a, layout: '2006'}\n", "a: layout needs the date type"},
+		{"fields:\n  - {name: a, attr: x, html: true}\n", "a: both attr and html set"},
+		{"fields:\n  - {name: a, trim: true, fields: [{name: b}]}\n",
+			"a: a field with fields takes no value options"},
+		{"fields:\n  - {name: a, fields: [{name: b, type: x}]}\n", "a.b: unknown type \"x\""},
+		{"fields:\n  - {name: a, selector: p}\n", "yaml: unmarshal errors:\n  "},
+		{`{"fields": [{"name": "a", "bogus": 1}]}`, `json: unknown field "bogus"`},
+	}
+	for i, tc := range tab {
+		_, err := Parse([]byte(tc.recipe))
+		if err == nil {
+			t.Errorf("tc[%d]: no error", i)
+			continue
+		}
+		res := err.Error()
+		if strings.HasSuffix(tc.exp, ": ") || strings.HasSuffix(tc.exp, "  ") {
+			res = res[:min(len(res), len(tc.exp))]
+		}
+		if res != tc.exp {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Package sanitize cleans html.Node trees according to allowlist policies.
+//
+// Scripts, styles, embedded objects, comments, event handler attributes
+// and URLs with disallowed schemes, like javascript:, are always removed,
+// whatever the policy says.
+package sanitize
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+	"github.com/wkhere/htmlx/internal/elem"
+)
+
+// Mode says what happens to elements not allowed by the policy.
+type Mode int
+
+const (
+	// Strip removes the element, keeping its sanitized content.
+	Strip Mode = iota
+	// Escape keeps the element tags as text, like &lt;tag&gt;.
+	Escape
+)
+
+// Target says how the target attribute of links is handled.
+type Target int
+
+const (
+	// KeepTarget keeps target only if the policy allows the attribute.
+	KeepTarget Target = iota
+	// StripTarget removes target from links.
+	StripTarget
+	// BlankTarget makes links open in a new window,
+	// adding rel="noopener noreferrer".
+	BlankTarget
+)
+
+type Policy struct {
+	// Elements maps allowed element names to the attributes
+	// allowed on them.
+	Elements map[string][]string
+
+	// GlobalAttrs are allowed on every allowed element.
+	GlobalAttrs []string
+
+	// URLSchemes lists schemes allowed in URL attributes, like href.
+	URLSchemes []string
+
+	// AllowRelativeURLs allows URLs without a scheme.
+	AllowRelativeURLs bool
+
+	// NoFollow adds rel="nofollow" to links.
+	NoFollow bool
+
+	Target Target
+	Mode   Mode
+}
+
+// UGCPolicy returns a policy suitable for user generated content:
+// common formatting, lists, tables, links and images.
+func UGCPolicy() *Policy {
+	p := &Policy{
+		Elements: map[string][]string{
+			"a":          {"href", "title"},
+			"abbr":       {"title"},
+			"blockquote": {"cite"},
+			"img":        {"src", "alt", "title", "width", "height"},
+			"ol":         {"start", "reversed"},
+			"q":          {"cite"},
+			"td":         {"colspan", "rowspan"},
+			"th":         {"colspan", "rowspan", "scope"},
+			"time":       {"datetime"},
+			"del":        {"cite", "datetime"},
+			"ins":        {"cite", "datetime"},
+		},
+		GlobalAttrs:       []string{"dir", "lang"},
+		URLSchemes:        []string{"http", "https", "mailto"},
+		AllowRelativeURLs: true,
+		NoFollow:          true,
+	}
+	for _, el := range strings.Fields(`
+		b br caption cite code dd dfn div dl dt em figcaption figure
+		h1 h2 h3 h4 h5 h6 hr i kbd li mark p pre s samp small span strong
+		sub sup table tbody tfoot thead tr u ul`) {
+		p.Elements[el] = nil
+	}
+	return p
+}
+
+// StrictPolicy returns a policy allowing no elements, leaving text only.
+func StrictPolicy() *Policy {
+	return &Policy{}
+}
+
+// Sanitize cleans all the descendants of the node wrapped by f,
+// modifying the tree in place. The node itself is left intact.
+func (p *Policy) Sanitize(f htmlx.Finder) {
+	if f.IsEmpty() {
+		return
+	}
+	p.clean(f.Node)
+}
+
+// SanitizeString parses s as a body fragment, sanitizes it
+// and renders the result.
+func (p *Policy) SanitizeString(s string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	p.Sanitize(htmlx.FinderFromNode(body))
+
+	var b strings.Builder
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (p *Policy) clean(parent *html.Node) {
+	var next *html.Node
+	for c := parent.FirstChild; c != nil; c = next {
+		next = c.NextSibling
+
+		switch c.Type {
+		case html.TextNode:
+			continue
+		case html.ElementNode:
+		default:
+			parent.RemoveChild(c)
+			continue
+		}
+
+		switch {
+		case c.Namespace != "" || dangerous(c):
+			parent.RemoveChild(c)
+
+		case p.allowed(c):
+			p.cleanAttrs(c)
+			p.clean(c)
+
+		case p.Mode == Escape:
+			p.clean(c)
+			parent.InsertBefore(textNode(startTag(c)), c)
+			unwrap(c)
+			if !elem.Void(c) {
+				parent.InsertBefore(textNode("</"+c.Data+">"), next)
+			}
+
+		default:
+			p.clean(c)
+			unwrap(c)
+		}
+	}
+}
+
+func (p *Policy) allowed(n *html.Node) bool {
+	_, ok := p.Elements[n.Data]
+	return ok
+}
+
+func (p *Policy) cleanAttrs(n *html.Node) {
+	allowed := p.Elements[n.Data]
+	aa := n.Attr[:0]
+	for _, a := range n.Attr {
+		k := strings.ToLower(a.Key)
+		switch {
+		case a.Namespace != "", strings.HasPrefix(k, "on"):
+			continue
+		case !slices.Contains(allowed, k) && !slices.Contains(p.GlobalAttrs, k):
+			continue
+		case k == "target" && p.Target != KeepTarget:
+			continue
+		case k == "style" && !safeStyle(a.Val):
+			continue
+		case k == "srcset" && !p.safeSrcset(a.Val):
+			continue
+		case urlAttrs[k] && !p.safeURL(a.Val):
+			continue
+		}
+		aa = append(aa, a)
+	}
+	n.Attr = aa
+
+	if n.DataAtom != atom.A || !attr.L(n.Attr).Exists("href") {
+		return
+	}
+	if p.Target == BlankTarget {
+		setAttr(n, "target", "_blank")
+		addWords(n, "rel", "noopener", "noreferrer")
+	}
+	if p.NoFollow {
+		addWords(n, "rel", "nofollow")
+	}
+}
+
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true,
+	"formaction": true, "poster": true, "background": true,
+	"longdesc": true, "data": true, "codebase": true, "usemap": true,
+	"manifest": true, "icon": true, "ping": true, "xmlns": true,
+}
+
+// safeURL checks the URL scheme the way browsers see it,
+// ignoring whitespace and control characters.
+func (p *Policy) safeURL(s string) bool {
+	clean := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+
+	i := strings.IndexAny(clean, ":/?#")
+	if i < 0 || clean[i] != ':' {
+		return p.AllowRelativeURLs
+	}
+	scheme := strings.ToLower(clean[:i])
+	return slices.Contains(p.URLSchemes, scheme)
+}
+
+func (p *Policy) safeSrcset(s string) bool {
+	for _, cand := range strings.Split(s, ",") {
+		fields := strings.Fields(cand)
+		if len(fields) > 0 && !p.safeURL(fields[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func safeStyle(s string) bool {
+	clean := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	for _, bad := range []string{
+		"expression(", "javascript:", "vbscript:", "behavior:",
+		"-moz-binding", "url(", "@import", "\\",
+	} {
+		if strings.Contains(clean, bad) {
+			return false
+		}
+	}
+	return true
+}
+
+// dangerous reports elements removed together with their content.
+func dangerous(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Template, atom.Noscript,
+		atom.Iframe, atom.Frame, atom.Frameset, atom.Object, atom.Embed,
+		atom.Applet, atom.Param, atom.Head, atom.Title, atom.Meta,
+		atom.Link, atom.Base, atom.Svg, atom.Math, atom.Textarea,
+		atom.Select, atom.Xmp, atom.Noembed, atom.Noframes,
+		atom.Plaintext:
+		return true
+	}
+	return false
+}
+
+func unwrap(n *html.Node) {
+	parent := n.Parent
+	for c := n.FirstChild; c != nil; c = n.FirstChild {
+		n.RemoveChild(c)
+		parent.InsertBefore(c, n)
+	}
+	parent.RemoveChild(n)
+}
+
+func startTag(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		b.WriteString(" " + a.Key + `="` + a.Val + `"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func textNode(s string) *html.Node {
+	return &html.Node{Type: html.TextNode, Data: s}
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func addWords(n *html.Node, key string, words ...string) {
+	v, _ := attr.L(n.Attr).Val(key)
+	ww := strings.Fields(v)
+	for _, w := range words {
+		if !slices.Contains(ww, w) {
+			ww = append(ww, w)
+		}
+	}
+	setAttr(n, key, strings.Join(ww, " "))
+}
@@ -0,0 +1,126 @@
+package sanitize
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+)
+
+func TestXSSCorpus(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "testdata", "xss.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	policies := map[string]*Policy{
+		"ugc":    UGCPolicy(),
+		"strict": StrictPolicy(),
+		"escape": func() *Policy { p := UGCPolicy(); p.Mode = Escape; return p }(),
+		"styled": func() *Policy {
+			p := UGCPolicy()
+			p.GlobalAttrs = append(p.GlobalAttrs, "style", "background")
+			p.Elements["form"] = []string{"action"}
+			p.Elements["button"] = []string{"formaction"}
+			p.Elements["video"] = []string{"poster", "src"}
+			return p
+		}(),
+	}
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		payload := sc.Text()
+		for name, p := range policies {
+			out, err := p.SanitizeString(payload)
+			if err != nil {
+				t.Errorf("line %d, %s: %v", line, name, err)
+				continue
+			}
+			if bad := unsafe(t, out); bad != "" {
+				t.Errorf("line %d, %s: %s in output:\n%s", line, name, bad, out)
+			}
+			again, _ := p.SanitizeString(out)
+			if again != out {
+				t.Errorf("line %d, %s: not idempotent:\n%s\n%s", line, name, out, again)
+			}
+		}
+	}
+}
+
+// unsafe reparses sanitized output and reports what makes it unsafe.
+func unsafe(t *testing.T, s string) string {
+	t.Helper()
+	top, err := htmlx.FinderFromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := range top.FindAll(func(h *html.Node) bool { return h.Type == html.ElementNode }) {
+		switch f.DataAtom {
+		case atom.Html, atom.Head, atom.Body:
+		case atom.Script, atom.Style, atom.Iframe, atom.Object, atom.Embed,
+			atom.Svg, atom.Math, atom.Meta, atom.Link, atom.Base, atom.Frame:
+			return "<" + f.Data + ">"
+		}
+		for _, a := range f.Node.Attr {
+			k := strings.ToLower(a.Key)
+			v := strings.ToLower(strings.Join(strings.Fields(a.Val), ""))
+			switch {
+			case strings.HasPrefix(k, "on"):
+				return "handler " + k
+			case strings.Contains(v, "javascript:"), strings.Contains(v, "vbscript:"),
+				strings.HasPrefix(v, "data:"), strings.Contains(v, "expression("):
+				return "attribute " + k + "=" + a.Val
+			}
+		}
+	}
+	return ""
+}
+
+func TestPolicies(t *testing.T) {
+	blank := UGCPolicy()
+	blank.Target = BlankTarget
+	noFollow := UGCPolicy()
+	noFollow.NoFollow = false
+	noFollow.Elements["a"] = append(noFollow.Elements["a"], "target")
+
+	tab := []struct {
+		p       *Policy
+		in, out string
+	}{
+		{UGCPolicy(), `<p>hi <b onclick="x()">there</b><script>x()</script></p>`,
+			`<p>hi <b>there</b></p>`},
+		{UGCPolicy(), `<div><blink>old <i>school</i></blink></div>`,
+			`<div>old <i>school</i></div>`},
+		{UGCPolicy(), `<a href="/rel" rel="me" target="_top">x</a>`,
+			`<a href="/rel" rel="nofollow">x</a>`},
+		{blank, `<a href="http://a.b/">x</a><a name="n">n</a>`,
+			`<a href="http://a.b/" target="_blank" rel="noopener noreferrer nofollow">x</a><a>n</a>`},
+		{noFollow, `<a href="x" target="_top">x</a>`,
+			`<a href="x" target="_top">x</a>`},
+		{func() *Policy { p := UGCPolicy(); p.AllowRelativeURLs = false; return p }(),
+			`<a href="/x">x</a><img src="https://a.b/i.png">`,
+			`<a>x</a><img src="https://a.b/i.png"/>`},
+		{func() *Policy { p := UGCPolicy(); p.Mode = Escape; return p }(),
+			`<p><blink class="x">a <b>b</b></blink><br/></p>`,
+			`<p>&lt;blink class=&#34;x&#34;&gt;a <b>b</b>&lt;/blink&gt;<br/></p>`},
+		{StrictPolicy(), `<h1>Title</h1><!-- c --><p>a &amp; <a href="x">b</a></p>`,
+			`Titlea &amp; b`},
+	}
+
+	for i, tc := range tab {
+		out, err := tc.p.SanitizeString(tc.in)
+		if err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if out != tc.out {
+			t.Errorf("tc[%d] mismatch:\ngot %s\nexp %s", i, out, tc.out)
+		}
+	}
+}
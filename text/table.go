@@ -0,0 +1,159 @@
+package text
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type tableRow struct {
+	cells  []string
+	header bool
+}
+
+// table renders an ASCII grid, shrinking the widest columns
+// and wrapping their cells when the table does not fit the width.
+func (r *renderer) table(n *html.Node, width int) (bb []block) {
+	var rows []tableRow
+	var caption string
+
+	var walk func(*html.Node, bool)
+	walk = func(n *html.Node, head bool) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			switch ch.DataAtom {
+			case atom.Caption:
+				in := &inline{space: true}
+				r.children(in, ch)
+				caption = in.String()
+			case atom.Thead:
+				walk(ch, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(ch, false)
+			case atom.Tr:
+				rows = append(rows, r.tableRow(ch, head))
+			}
+		}
+	}
+	walk(n, false)
+
+	if caption != "" {
+		bb = append(bb, block{lines: wrap(caption, width)})
+	}
+	if len(rows) == 0 {
+		return bb
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row.cells))
+	}
+	widths := make([]int, cols)
+	for _, row := range rows {
+		for i, c := range row.cells {
+			for _, l := range strings.Split(c, "\n") {
+				widths[i] = max(widths[i], utf8.RuneCountInString(l))
+			}
+		}
+	}
+	fit(widths, width-(3*cols+1))
+
+	sep := func(c string) string {
+		var b strings.Builder
+		b.WriteByte('+')
+		for _, w := range widths {
+			b.WriteString(strings.Repeat(c, w+2))
+			b.WriteByte('+')
+		}
+		return b.String()
+	}
+
+	lines := []string{sep("-")}
+	for i, row := range rows {
+		cells := make([][]string, cols)
+		height := 1
+		for j := range cols {
+			if j < len(row.cells) {
+				cells[j] = wrap(row.cells[j], widths[j])
+			}
+			height = max(height, len(cells[j]))
+		}
+		for k := range height {
+			var b strings.Builder
+			b.WriteByte('|')
+			for j, w := range widths {
+				s := ""
+				if k < len(cells[j]) {
+					s = cells[j][k]
+				}
+				b.WriteString(" " + s)
+				b.WriteString(strings.Repeat(" ", max(w-utf8.RuneCountInString(s), 0)+1))
+				b.WriteByte('|')
+			}
+			lines = append(lines, b.String())
+		}
+		if row.header && (i+1 == len(rows) || !rows[i+1].header) {
+			lines = append(lines, sep("="))
+		} else {
+			lines = append(lines, sep("-"))
+		}
+	}
+	return append(bb, block{lines: lines})
+}
+
+func (r *renderer) tableRow(tr *html.Node, head bool) (row tableRow) {
+	row.header = head
+	allTh := true
+	for td := tr.FirstChild; td != nil; td = td.NextSibling {
+		if td.DataAtom != atom.Td && td.DataAtom != atom.Th {
+			continue
+		}
+		if td.DataAtom != atom.Th {
+			allTh = false
+		}
+		in := &inline{space: true}
+		r.cell(in, td)
+		row.cells = append(row.cells, in.String())
+	}
+	if len(row.cells) > 0 && allTh {
+		row.header = true
+	}
+	return row
+}
+
+func (r *renderer) cell(in *inline, n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && isBlock(ch) {
+			if in.b.Len() > 0 {
+				in.hardBreak()
+			}
+			r.cell(in, ch)
+			continue
+		}
+		r.inline(in, ch)
+	}
+}
+
+// fit shrinks the widest columns until their sum fits the total,
+// keeping each column at least a few characters wide.
+func fit(widths []int, total int) {
+	const minWidth = 5
+	sum := 0
+	for _, w := range widths {
+		sum += w
+	}
+	for sum > total {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minWidth {
+			return
+		}
+		widths[widest]--
+		sum--
+	}
+}
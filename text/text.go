@@ -0,0 +1,420 @@
+// Package text renders HTML subtrees as readable plain text,
+// in the manner of `lynx -dump`: paragraphs wrapped to a width,
+// indented lists, ASCII tables and footnoted links.
+package text
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/wkhere/htmlx"
+	"github.com/wkhere/htmlx/attr"
+)
+
+type LinkStyle int
+
+const (
+	// Footnotes marks links like text[1] and lists the URLs
+	// under References at the end.
+	Footnotes LinkStyle = iota
+	// Inline puts the URL right after the link text, like text (url).
+	Inline
+	// NoLinks drops the URLs.
+	NoLinks
+)
+
+const DefaultWidth = 78
+
+type Renderer struct {
+	// Width is the maximum line width; DefaultWidth if zero.
+	// Preformatted text and long words may exceed it.
+	Width int
+
+	LinkStyle LinkStyle
+
+	// Base, if set, is used to resolve relative link URLs.
+	Base *url.URL
+}
+
+// String renders the subtree using default options.
+func String(f htmlx.Finder) string {
+	var b strings.Builder
+	Renderer{}.Render(&b, f)
+	return b.String()
+}
+
+// Render writes the subtree rooted at f as plain text.
+func (r Renderer) Render(w io.Writer, f htmlx.Finder) error {
+	if f.IsEmpty() {
+		return nil
+	}
+	width := r.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	rr := &renderer{Renderer: r, refIdx: map[string]int{}}
+
+	var bb []block
+	switch n := f.Node; {
+	case n.Type != html.ElementNode:
+		bb = rr.blocks(n, width)
+	case skipped(n):
+	case isBlock(n):
+		bb = rr.block(n, width)
+	default:
+		in := &inline{space: true}
+		rr.inline(in, n)
+		bb = rr.para(in, width)
+	}
+
+	if len(rr.refs) > 0 && r.LinkStyle == Footnotes {
+		refs := []string{"References", ""}
+		digits := len(strconv.Itoa(len(rr.refs)))
+		for i, u := range rr.refs {
+			refs = append(refs, fmt.Sprintf("%*d. %s", digits+3, i+1, u))
+		}
+		bb = append(bb, block{lines: refs})
+	}
+
+	var b strings.Builder
+	for i, x := range bb {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, l := range x.lines {
+			b.WriteString(strings.TrimRight(l, " "))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type renderer struct {
+	Renderer
+	refs   []string
+	refIdx map[string]int
+}
+
+type block struct {
+	lines []string
+	list  bool
+}
+
+func (r *renderer) blocks(n *html.Node, width int) (bb []block) {
+	in := &inline{space: true}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		switch {
+		case ch.Type == html.ElementNode && skipped(ch):
+		case ch.Type == html.ElementNode && isBlock(ch):
+			bb = append(bb, r.para(in, width)...)
+			in = &inline{space: true}
+			bb = append(bb, r.block(ch, width)...)
+		default:
+			r.inline(in, ch)
+		}
+	}
+	return append(bb, r.para(in, width)...)
+}
+
+func (r *renderer) block(n *html.Node, width int) []block {
+	switch n.DataAtom {
+	case atom.H1, atom.H2:
+		in := &inline{space: true}
+		r.children(in, n)
+		lines := wrap(in.String(), width)
+		if len(lines) == 0 {
+			return nil
+		}
+		under := "="
+		if n.DataAtom == atom.H2 {
+			under = "-"
+		}
+		w := 0
+		for _, l := range lines {
+			w = max(w, utf8.RuneCountInString(l))
+		}
+		return []block{{lines: append(lines, strings.Repeat(under, w))}}
+
+	case atom.Ul, atom.Ol:
+		return r.list(n, width)
+
+	case atom.Blockquote, atom.Dd:
+		const indent = "    "
+		bb := r.blocks(n, width-len(indent))
+		for i := range bb {
+			for j, l := range bb[i].lines {
+				bb[i].lines[j] = indent + l
+			}
+		}
+		return bb
+
+	case atom.Pre:
+		s := strings.TrimRight(textOf(n), "\n")
+		return []block{{lines: strings.Split(expandTabs(s), "\n")}}
+
+	case atom.Table:
+		return r.table(n, width)
+
+	case atom.Hr:
+		return []block{{lines: []string{strings.Repeat("-", width)}}}
+	}
+	return r.blocks(n, width)
+}
+
+func (r *renderer) list(n *html.Node, width int) []block {
+	start := 1
+	if s, ok := attr.L(n.Attr).Val("start"); ok {
+		if i, err := strconv.Atoi(s); err == nil {
+			start = i
+		}
+	}
+
+	var items []*html.Node
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type == html.ElementNode {
+			items = append(items, li)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	digits := len(strconv.Itoa(start + len(items) - 1))
+
+	var lines []string
+	for i, li := range items {
+		marker := "  * "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("  %*d. ", digits, start+i)
+		}
+		indent := strings.Repeat(" ", len(marker))
+
+		var content []string
+		for j, b := range r.blocks(li, width-len(marker)) {
+			if j > 0 && !b.list {
+				content = append(content, "")
+			}
+			content = append(content, b.lines...)
+		}
+		if len(content) == 0 {
+			content = []string{""}
+		}
+		lines = append(lines, marker+content[0])
+		for _, l := range content[1:] {
+			if l == "" {
+				lines = append(lines, l)
+			} else {
+				lines = append(lines, indent+l)
+			}
+		}
+	}
+	return []block{{lines: lines, list: true}}
+}
+
+func (r *renderer) para(in *inline, width int) []block {
+	lines := wrap(in.String(), width)
+	if len(lines) == 0 {
+		return nil
+	}
+	return []block{{lines: lines}}
+}
+
+func (r *renderer) ref(u string) int {
+	if i, ok := r.refIdx[u]; ok {
+		return i
+	}
+	r.refs = append(r.refs, u)
+	r.refIdx[u] = len(r.refs)
+	return len(r.refs)
+}
+
+func (r *renderer) resolve(s string) string {
+	s = strings.TrimSpace(s)
+	if r.Base == nil {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return r.Base.ResolveReference(u).String()
+}
+
+// wrap breaks text into lines no longer than width, if possible.
+// Newlines in the text are hard breaks.
+func wrap(s string, width int) (lines []string) {
+	if s == "" {
+		return nil
+	}
+	width = max(width, 1)
+	for _, seg := range strings.Split(s, "\n") {
+		var line strings.Builder
+		n := 0
+		for _, w := range strings.Fields(seg) {
+			wn := utf8.RuneCountInString(w)
+			if n > 0 && n+1+wn > width {
+				lines = append(lines, line.String())
+				line.Reset()
+				n = 0
+			}
+			if n > 0 {
+				line.WriteByte(' ')
+				n++
+			}
+			line.WriteString(w)
+			n += wn
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, c := range s {
+		switch c {
+		case '\t':
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n':
+			b.WriteRune(c)
+			col = 0
+		default:
+			b.WriteRune(c)
+			col++
+		}
+	}
+	return b.String()
+}
+
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Body,
+		atom.Dd, atom.Details, atom.Dialog, atom.Div, atom.Dl, atom.Dt,
+		atom.Fieldset, atom.Figcaption, atom.Figure, atom.Footer, atom.Form,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Header,
+		atom.Hgroup, atom.Hr, atom.Html, atom.Li, atom.Main, atom.Nav,
+		atom.Ol, atom.P, atom.Pre, atom.Section, atom.Summary, atom.Table,
+		atom.Ul, atom.Tr, atom.Caption:
+		return true
+	}
+	return false
+}
+
+func skipped(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Template, atom.Noscript,
+		atom.Title, atom.Meta, atom.Link:
+		return true
+	}
+	return false
+}
+
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// inline accumulates inline text, collapsing whitespace;
+// hard breaks are kept as newlines.
+type inline struct {
+	b     strings.Builder
+	space bool
+}
+
+func (in *inline) text(s string) {
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if !in.space {
+				in.b.WriteByte(' ')
+				in.space = true
+			}
+			continue
+		}
+		in.b.WriteRune(c)
+		in.space = false
+	}
+}
+
+func (in *inline) hardBreak() {
+	in.b.WriteByte('\n')
+	in.space = true
+}
+
+func (in *inline) String() string {
+	s := strings.TrimSpace(in.b.String())
+	return strings.ReplaceAll(strings.ReplaceAll(s, " \n", "\n"), "\n ", "\n")
+}
+
+func (r *renderer) children(in *inline, n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		r.inline(in, ch)
+	}
+}
+
+func (r *renderer) inline(in *inline, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		in.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	a := attr.L(n.Attr)
+	switch n.DataAtom {
+	case atom.Br:
+		in.hardBreak()
+	case atom.Img:
+		if alt, _ := a.Val("alt"); strings.TrimSpace(alt) != "" {
+			in.text("[" + strings.TrimSpace(alt) + "]")
+		}
+	case atom.A:
+		r.children(in, n)
+		href, ok := a.Val("href")
+		if !ok || strings.HasPrefix(href, "#") ||
+			strings.HasPrefix(href, "javascript:") {
+			return
+		}
+		u := r.resolve(href)
+		switch r.LinkStyle {
+		case Footnotes:
+			in.text("[" + strconv.Itoa(r.ref(u)) + "]")
+		case Inline:
+			if strings.TrimSpace(textOf(n)) != u {
+				in.text(" (" + u + ")")
+			}
+		}
+	default:
+		if !skipped(n) {
+			r.children(in, n)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wkhere/htmlx"
+)
+
+func TestRender(t *testing.T) {
+	tab := []struct {
+		html string
+		r    Renderer
+		text string
+	}{
+		{`<p>one two three four five six</p><p>x<br>y</p>`, Renderer{Width: 10},
+			"one two\nthree four\nfive six\n\nx\ny\n"},
+		{`<h1>Title</h1><h2>Sub</h2><hr>`, Renderer{Width: 5},
+			"Title\n=====\n\nSub\n---\n\n-----\n"},
+		{`<ul><li>a</li><li>b<ol start="9"><li>c</li><li>d</li></ol></li></ul>`, Renderer{},
+			"  * a\n  * b\n       9. c\n      10. d\n"},
+		{`<ul><li>long item text here</li></ul>`, Renderer{Width: 12},
+			"  * long\n    item\n    text\n    here\n"},
+		{"<pre>  keep\n\tthis</pre><blockquote>q</blockquote>", Renderer{},
+			"  keep\n        this\n\n    q\n"},
+		{`<p><a href="/a">A</a> <a href="#x">X</a> <a href="/a">again</a> <img alt="pic" src="p"></p>`,
+			Renderer{}, "A[1] X again[1] [pic]\n\nReferences\n\n   1. /a\n"},
+		{`<p><a href="/a">A</a> <a href="http://b/">http://b/</a></p>`,
+			Renderer{LinkStyle: Inline}, "A (/a) http://b/\n"},
+		{`<p><a href="/a">A</a></p>`, Renderer{LinkStyle: NoLinks}, "A\n"},
+		{`<table><tr><th>k</th><th>value</th></tr><tr><td>a</td><td>bb</td></tr></table>`, Renderer{},
+			"+---+-------+\n| k | value |\n+===+=======+\n| a | bb    |\n+---+-------+\n"},
+		{`<table><tr><td>x</td><td>one two three</td></tr></table>`, Renderer{Width: 16},
+			"+---+----------+\n| x | one two  |\n|   | three    |\n+---+----------+\n"},
+	}
+
+	for i, tc := range tab {
+		f, _ := htmlx.FinderFromString(tc.html)
+		var b strings.Builder
+		if err := tc.r.Render(&b, f); err != nil {
+			t.Errorf("tc[%d]: %v", i, err)
+		}
+		if res := b.String(); res != tc.text {
+			t.Errorf("tc[%d] mismatch:\ngot:\n%s\nexp:\n%s", i, res, tc.text)
+		}
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	if s := String(htmlx.Finder{}); s != "" {
+		t.Errorf("got %q, exp empty", s)
+	}
+}